@@ -2,14 +2,31 @@ package main
 
 import (
 	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/mutexkv"
+)
+
+var (
+	// retryMinDelay is the base delay for the exponential backoff between retries
+	retryMinDelay = 1 * time.Second
+	// retryMaxDelay caps the delay between retries, including any Retry-After header
+	retryMaxDelay = 30 * time.Second
 )
 
 // InstaclustrClient is a client for interfacing with the Instaclustr API
 type InstaclustrClient struct {
 	config Config
 	client *http.Client
+	// firewallLocks serializes firewall changes per cluster ID
+	firewallLocks *mutexkv.MutexKV
 }
 
 // FirewallClient creates a client for interfacing with the Instaclustr Firewall API
@@ -34,33 +51,59 @@ func (c *InstaclustrClient) ClusterClient() *ClusterClient {
 }
 
 func (c *InstaclustrClient) doGet(path string) (*http.Response, error) {
-	url := strings.Join([]string{c.config.URL, path}, "/")
-	request, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	c.configureRequest(request)
-	return c.client.Do(request)
+	return c.do(http.MethodGet, path, nil)
 }
 
 func (c *InstaclustrClient) doPost(path string, body []byte) (*http.Response, error) {
-	url := strings.Join([]string{c.config.URL, path}, "/")
-	request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
-	}
-	c.configureRequest(request)
-	return c.client.Do(request)
+	return c.do(http.MethodPost, path, body)
+}
+
+func (c *InstaclustrClient) doPut(path string, body []byte) (*http.Response, error) {
+	return c.do(http.MethodPut, path, body)
 }
 
 func (c *InstaclustrClient) doDelete(path string, body []byte) (*http.Response, error) {
+	return c.do(http.MethodDelete, path, body)
+}
+
+// doDownload gets a file from the API, accepting any content type
+func (c *InstaclustrClient) doDownload(path string) (*http.Response, error) {
+	return c.doAccept(http.MethodGet, path, nil, "*/*")
+}
+
+func (c *InstaclustrClient) do(method, path string, body []byte) (*http.Response, error) {
+	return c.doAccept(method, path, body, "application/json")
+}
+
+// doAccept sends a request to the API, retrying throttled and transient failures
+// with exponential backoff up to the configured number of retries
+func (c *InstaclustrClient) doAccept(method, path string, body []byte, accept string) (*http.Response, error) {
 	url := strings.Join([]string{c.config.URL, path}, "/")
-	request, err := http.NewRequest(http.MethodDelete, url, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		request, err := http.NewRequest(method, url, reader)
+		if err != nil {
+			return nil, err
+		}
+		c.configureRequest(request)
+		request.Header.Set("Accept", accept)
+		response, err := c.client.Do(request)
+		if attempt >= c.config.MaxRetries || !shouldRetry(method, response, err) {
+			return response, err
+		}
+		delay := retryDelay(attempt, response)
+		if err != nil {
+			log.Printf("[WARN] %s %s failed, retrying in %s: %s", method, url, delay, err)
+		} else {
+			log.Printf("[WARN] %s %s returned %d, retrying in %s", method, url, response.StatusCode, delay)
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+		time.Sleep(delay)
 	}
-	c.configureRequest(request)
-	return c.client.Do(request)
 }
 
 func (c *InstaclustrClient) configureRequest(request *http.Request) {
@@ -68,3 +111,66 @@ func (c *InstaclustrClient) configureRequest(request *http.Request) {
 	request.Header.Set("Content-Type", "application/json")
 	request.SetBasicAuth(c.config.AccessKey, c.config.SecretKey)
 }
+
+// shouldRetry reports whether a request may be sent again. Idempotent requests
+// are retried on connection errors, throttling and gateway failures. Other
+// requests are only retried when throttled, as the API rejected them unprocessed.
+func shouldRetry(method string, response *http.Response, err error) bool {
+	if err == nil && response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if !isIdempotent(method) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before the next attempt, honoring the
+// Retry-After header when present and otherwise using full jitter backoff
+func retryDelay(attempt int, response *http.Response) time.Duration {
+	if response != nil {
+		if delay, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+			if delay > retryMaxDelay {
+				return retryMaxDelay
+			}
+			return delay
+		}
+	}
+	backoff := retryMinDelay << uint(attempt)
+	if backoff <= 0 || backoff > retryMaxDelay {
+		backoff = retryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
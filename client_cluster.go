@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"strings"
 )
 
 // ClusterClient creates a client for interfacing with the Instaclustr Cluster API
@@ -25,6 +26,7 @@ type CreateClusterRequest struct {
 // CreateClusterRequestRegion is the region sub section for cluster creation
 type CreateClusterRequestRegion struct {
 	Datacenter                    string                                     `json:"dataCentre"`
+	DatacenterCustomName          string                                     `json:"dataCentreCustomName,omitempty"`
 	AuthnAuthz                    bool                                       `json:"authnAuthz,string"`
 	ClientEncryption              bool                                       `json:"clientEncryption,string"`
 	UsePrivateBroadcastRPCAddress bool                                       `json:"usePrivateBroadcastRPCAddress,string"`
@@ -40,6 +42,40 @@ type CreateClusterRequestRegionRackAllocation struct {
 	NodeCount int    `json:"nodeCount,string"`
 }
 
+// AddDatacenterRequest is the request object for adding a datacenter to an existing cluster
+type AddDatacenterRequest struct {
+	Provider string `json:"provider"`
+	Account  string `json:"account,omitempty"`
+	Size     string `json:"size"`
+	CreateClusterRequestRegion
+}
+
+// AddDatacenterResponse is the response from adding a datacenter to a cluster
+type AddDatacenterResponse struct {
+	ID string `json:"id"`
+}
+
+// AddNodesRequest is the request object for adding nodes to the racks of a datacenter
+type AddNodesRequest struct {
+	RackAllocations []CreateClusterRequestRegionRackAllocation `json:"rackAllocation"`
+}
+
+// ResizeDatacenterRequest is the request object for resizing the nodes of a datacenter
+type ResizeDatacenterRequest struct {
+	NewNodeSize       string `json:"newNodeSize"`
+	ConcurrentResizes int    `json:"concurrentResizes"`
+}
+
+// ResizeDatacenterResponse is the response from starting a datacenter resize
+type ResizeDatacenterResponse struct {
+	OperationID string `json:"operationId"`
+}
+
+// UpgradeClusterRequest is the request object for upgrading the software version of a cluster
+type UpgradeClusterRequest struct {
+	Version string `json:"version"`
+}
+
 // CreateClusterResponse is the response from provisioning a cluster
 type CreateClusterResponse struct {
 	ID string `json:"id"`
@@ -47,15 +83,16 @@ type CreateClusterResponse struct {
 
 // ClusterStatus is the returned object for a cluster
 type ClusterStatus struct {
-	ID                         string         `json:"id"`
-	ClusterName                string         `json:"clusterName"`
-	ClusterNetwork             ClusterNetwork `json:"clusterNetwork"`
-	ClusterStatus              string         `json:"clusterStatus"`
-	CassandraVersion           string         `json:"cassandraVersion"`
-	Username                   string         `json:"username"`
-	InstaclustrUserPassword    string         `json:"instaclustrUserPassword"`
-	ClusterCertificateDownload string         `json:"clusterCertificateDownload"`
-	Datacenters                []Datacenter   `json:"dataCentres"`
+	ID                         string            `json:"id"`
+	ClusterName                string            `json:"clusterName"`
+	ClusterNetwork             ClusterNetwork    `json:"clusterNetwork"`
+	ClusterStatus              string            `json:"clusterStatus"`
+	CassandraVersion           string            `json:"cassandraVersion"`
+	Username                   string            `json:"username"`
+	InstaclustrUserPassword    string            `json:"instaclustrUserPassword"`
+	ClusterCertificateDownload string            `json:"clusterCertificateDownload"`
+	Datacenters                []Datacenter      `json:"dataCentres"`
+	Tags                       map[string]string `json:"tags"`
 }
 
 // ClusterNetwork is the network object for cluster
@@ -68,7 +105,10 @@ type ClusterNetwork struct {
 type Datacenter struct {
 	ID                            string           `json:"id"`
 	Name                          string           `json:"name"`
+	CustomName                    string           `json:"dataCentreCustomName"`
 	Provider                      string           `json:"provider"`
+	ProviderAccountName           string           `json:"providerAccountName"`
+	EncryptionKeyID               string           `json:"encryptionKeyId"`
 	ClientEncryption              bool             `json:"clientEncryption"`
 	PasswordAuthentication        bool             `json:"passwordAuthentication"`
 	UserAuthorization             bool             `json:"userAuthorization"`
@@ -110,8 +150,9 @@ func (c *ClusterClient) List() ([]*ClusterListStatus, error) {
 	}
 	defer response.Body.Close()
 	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return nil, fmt.Errorf("List Cluster did not return 200/202 [%d]:\n%s", response.StatusCode, string(responseData))
+	err = checkResponse("List Cluster", response, responseData)
+	if err != nil {
+		return nil, err
 	}
 	clusters := []*ClusterListStatus{}
 	err = json.Unmarshal(responseData, &clusters)
@@ -129,8 +170,9 @@ func (c *ClusterClient) Get(clusterID string) (*ClusterStatus, error) {
 	}
 	defer response.Body.Close()
 	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return nil, fmt.Errorf("Get Cluster did not return 200/202 [%d]:\n%s", response.StatusCode, string(responseData))
+	err = checkResponse("Get Cluster", response, responseData)
+	if err != nil {
+		return nil, err
 	}
 	cluster := &ClusterStatus{}
 	err = json.Unmarshal(responseData, cluster)
@@ -140,6 +182,27 @@ func (c *ClusterClient) Get(clusterID string) (*ClusterStatus, error) {
 	return cluster, nil
 }
 
+// DownloadCertificates downloads the certificate bundle from the cluster's
+// certificate download URL. The URL must be on the configured API, as the
+// request carries the API credentials.
+func (c *ClusterClient) DownloadCertificates(downloadURL string) ([]byte, error) {
+	prefix := strings.TrimSuffix(c.client.config.URL, "/") + "/"
+	if !strings.HasPrefix(downloadURL, prefix) {
+		return nil, fmt.Errorf("Certificate download URL %s is not on the configured API %s", downloadURL, c.client.config.URL)
+	}
+	response, err := c.client.doDownload(strings.TrimPrefix(downloadURL, prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseData, _ := ioutil.ReadAll(response.Body)
+	err = checkResponse("Download Cluster Certificates", response, responseData)
+	if err != nil {
+		return nil, err
+	}
+	return responseData, nil
+}
+
 // Delete deletes a cluster
 func (c *ClusterClient) Delete(clusterID string) error {
 	response, err := c.client.doDelete(clusterID, nil)
@@ -147,10 +210,8 @@ func (c *ClusterClient) Delete(clusterID string) error {
 		return err
 	}
 	defer response.Body.Close()
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return fmt.Errorf("Cluster DELETE did not return 200/202 [%d]", response.StatusCode)
-	}
-	return nil
+	responseData, _ := ioutil.ReadAll(response.Body)
+	return checkResponse("Delete Cluster", response, responseData)
 }
 
 // Create creates a new cluster
@@ -165,8 +226,9 @@ func (c *ClusterClient) Create(request CreateClusterRequest) (*CreateClusterResp
 	}
 	defer response.Body.Close()
 	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return nil, fmt.Errorf("Create Cluster did not return 200/202 [%d]:\n%s", response.StatusCode, string(responseData))
+	err = checkResponse("Create Cluster", response, responseData)
+	if err != nil {
+		return nil, err
 	}
 	cluster := &CreateClusterResponse{}
 	err = json.Unmarshal(responseData, cluster)
@@ -175,3 +237,96 @@ func (c *ClusterClient) Create(request CreateClusterRequest) (*CreateClusterResp
 	}
 	return cluster, nil
 }
+
+// AddDatacenter adds a new datacenter to an existing cluster
+func (c *ClusterClient) AddDatacenter(clusterID string, request AddDatacenterRequest) (*AddDatacenterResponse, error) {
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.doPost(strings.Join([]string{clusterID, "cluster-data-centres"}, "/"), bytes)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseData, _ := ioutil.ReadAll(response.Body)
+	err = checkResponse("Add Cluster Datacenter", response, responseData)
+	if err != nil {
+		return nil, err
+	}
+	datacenter := &AddDatacenterResponse{}
+	err = json.Unmarshal(responseData, datacenter)
+	if err != nil {
+		return nil, err
+	}
+	return datacenter, nil
+}
+
+// AddNodes adds nodes to the racks of an existing cluster datacenter
+func (c *ClusterClient) AddNodes(clusterID, datacenterID string, request AddNodesRequest) error {
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	response, err := c.client.doPost(strings.Join([]string{clusterID, "cluster-data-centres", datacenterID, "nodes"}, "/"), bytes)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	responseData, _ := ioutil.ReadAll(response.Body)
+	return checkResponse("Add Cluster Nodes", response, responseData)
+}
+
+// ResizeDatacenter starts a resize of the nodes in a cluster datacenter
+func (c *ClusterClient) ResizeDatacenter(clusterID, datacenterID string, request ResizeDatacenterRequest) (*ResizeDatacenterResponse, error) {
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.doPost(strings.Join([]string{clusterID, "cluster-data-centres", datacenterID, "resize"}, "/"), bytes)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseData, _ := ioutil.ReadAll(response.Body)
+	err = checkResponse("Resize Cluster Datacenter", response, responseData)
+	if err != nil {
+		return nil, err
+	}
+	resize := &ResizeDatacenterResponse{}
+	err = json.Unmarshal(responseData, resize)
+	if err != nil {
+		return nil, err
+	}
+	return resize, nil
+}
+
+// Upgrade starts an in place software upgrade of a cluster
+func (c *ClusterClient) Upgrade(clusterID string, request UpgradeClusterRequest) error {
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	response, err := c.client.doPost(strings.Join([]string{clusterID, "software-upgrade"}, "/"), bytes)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	responseData, _ := ioutil.ReadAll(response.Body)
+	return checkResponse("Upgrade Cluster", response, responseData)
+}
+
+// UpdateTags replaces the tags on a cluster
+func (c *ClusterClient) UpdateTags(clusterID string, tags map[string]string) error {
+	bytes, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	response, err := c.client.doPut(strings.Join([]string{clusterID, "tags"}, "/"), bytes)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	responseData, _ := ioutil.ReadAll(response.Body)
+	return checkResponse("Update Cluster Tags", response, responseData)
+}
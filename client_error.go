@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// APIError is returned when the Instaclustr API responds with an unexpected status
+type APIError struct {
+	Operation  string
+	StatusCode int
+	Method     string
+	Path       string
+	RequestID  string
+	Message    string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s did not return 200/202 [%d]", e.Operation, e.StatusCode)
+	if e.Method != "" {
+		fmt.Fprintf(&buf, " (%s %s", e.Method, e.Path)
+		if e.RequestID != "" {
+			fmt.Fprintf(&buf, ", request id %s", e.RequestID)
+		}
+		buf.WriteString(")")
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&buf, ": %s", e.Message)
+	} else if e.Body != "" {
+		fmt.Fprintf(&buf, ":\n%s", e.Body)
+	}
+	return buf.String()
+}
+
+// IsNotFound returns true if the error is an APIError for a missing object
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// checkResponse returns an APIError unless the response has a successful status code
+func checkResponse(operation string, response *http.Response, responseData []byte) error {
+	if response.StatusCode == http.StatusOK || response.StatusCode == http.StatusAccepted {
+		return nil
+	}
+	apiErr := &APIError{
+		Operation:  operation,
+		StatusCode: response.StatusCode,
+		RequestID:  requestID(response.Header),
+		Message:    errorMessage(responseData),
+		Body:       string(responseData),
+	}
+	if response.Request != nil {
+		apiErr.Method = response.Request.Method
+		apiErr.Path = response.Request.URL.Path
+	}
+	return apiErr
+}
+
+func requestID(header http.Header) string {
+	for _, key := range []string{"X-Request-Id", "X-Amzn-Requestid", "X-Correlation-Id"} {
+		if id := header.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// errorMessage extracts the error message from a JSON error response body
+func errorMessage(responseData []byte) string {
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(responseData, &body); err != nil {
+		return ""
+	}
+	for _, key := range []string{"message", "errorMessage", "error", "status"} {
+		if message, ok := body[key].(string); ok && message != "" {
+			return message
+		}
+	}
+	return ""
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
+	"log"
+	"net/http"
 	"strings"
+	"time"
 )
 
 // FirewallClient is a client for interacting with Firewall rules
@@ -14,8 +16,17 @@ type FirewallClient struct {
 
 // Firewall is the response from the Firewall API
 type Firewall struct {
-	Network string         `json:"network"`
-	Rules   []FirewallRule `json:"rules"`
+	Network         string         `json:"network,omitempty"`
+	SecurityGroupID string         `json:"securityGroupId,omitempty"`
+	Rules           []FirewallRule `json:"rules"`
+}
+
+// Source returns the network CIDR or AWS security group ID the rule authorizes
+func (f *Firewall) Source() string {
+	if f.SecurityGroupID != "" {
+		return f.SecurityGroupID
+	}
+	return f.Network
 }
 
 // FirewallRule is a collection inside Firewall
@@ -31,8 +42,9 @@ func (fc *FirewallClient) List(clusterID string) ([]*Firewall, error) {
 	}
 	defer response.Body.Close()
 	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return nil, fmt.Errorf("List Firewall Rules did not return 200/202 [%d]:\n%s", response.StatusCode, string(responseData))
+	err = checkResponse("List Firewall Rules", response, responseData)
+	if err != nil {
+		return nil, err
 	}
 	firewall := []*Firewall{}
 	err = json.Unmarshal(responseData, &firewall)
@@ -42,54 +54,72 @@ func (fc *FirewallClient) List(clusterID string) ([]*Firewall, error) {
 	return firewall, nil
 }
 
-// Create adds a firewall rule to a cluster for the provided network CIDR
-func (fc *FirewallClient) Create(clusterID, network string) error {
-	firewall := Firewall{
-		Network: network,
-		Rules: []FirewallRule{
-			FirewallRule{
-				Type: "CASSANDRA",
-			},
-		},
-	}
+// Create adds a firewall rule to a cluster for the provided network CIDR or
+// security group ID and rule types
+func (fc *FirewallClient) Create(clusterID, source string, ruleTypes []string) error {
+	firewall := newFirewall(source, ruleTypes)
 	bytes, err := json.Marshal(firewall)
 	if err != nil {
 		return err
 	}
-	response, err := fc.client.doPost(strings.Join([]string{clusterID, "firewallRules"}, "/"), bytes)
+	return fc.mutate("Create Firewall Rule", http.MethodPost, clusterID, bytes)
+}
+
+// Delete removes the rule types of a network or security group firewall rule from a cluster
+func (fc *FirewallClient) Delete(clusterID, source string, ruleTypes []string) error {
+	firewall := newFirewall(source, ruleTypes)
+	bytes, err := json.Marshal(firewall)
 	if err != nil {
 		return err
 	}
-	defer response.Body.Close()
-	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return fmt.Errorf("Create Firewall Rule did not return 200/202 [%d]:\n%s\n%s", response.StatusCode, string(responseData), string(bytes))
-	}
-	return nil
+	return fc.mutate("Delete Firewall Rule", http.MethodDelete, clusterID, bytes)
 }
 
-// Delete removes a network firewall rule from a cluster
-func (fc *FirewallClient) Delete(clusterID, network string) error {
+// newFirewall builds a firewall rule for a network CIDR or security group ID
+func newFirewall(source string, ruleTypes []string) Firewall {
 	firewall := Firewall{
-		Network: network,
-		Rules: []FirewallRule{
-			FirewallRule{
-				Type: "CASSANDRA",
-			},
-		},
+		Rules: firewallRules(ruleTypes),
 	}
-	bytes, err := json.Marshal(firewall)
-	if err != nil {
-		return err
+	if isSecurityGroupID(source) {
+		firewall.SecurityGroupID = source
+	} else {
+		firewall.Network = source
 	}
-	response, err := fc.client.doDelete(strings.Join([]string{clusterID, "firewallRules"}, "/"), bytes)
-	if err != nil {
-		return err
+	return firewall
+}
+
+func isSecurityGroupID(source string) bool {
+	return strings.HasPrefix(source, "sg-")
+}
+
+func firewallRules(ruleTypes []string) []FirewallRule {
+	rules := []FirewallRule{}
+	for _, ruleType := range ruleTypes {
+		rules = append(rules, FirewallRule{
+			Type: ruleType,
+		})
 	}
-	defer response.Body.Close()
-	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return fmt.Errorf("Delete Firewall Rule did not return 200/202 [%d]:\n%s", response.StatusCode, string(responseData))
+	return rules
+}
+
+// mutate sends a change to a cluster's firewall. Changes to the same cluster are
+// serialized, and retried while the API reports a conflicting change.
+func (fc *FirewallClient) mutate(operation, method, clusterID string, body []byte) error {
+	fc.client.firewallLocks.Lock(clusterID)
+	defer fc.client.firewallLocks.Unlock(clusterID)
+	path := strings.Join([]string{clusterID, "firewallRules"}, "/")
+	for attempt := 0; ; attempt++ {
+		response, err := fc.client.do(method, path, body)
+		if err != nil {
+			return err
+		}
+		responseData, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode != http.StatusConflict || attempt >= fc.client.config.MaxRetries {
+			return checkResponse(operation, response, responseData)
+		}
+		delay := retryDelay(attempt, response)
+		log.Printf("[WARN] %s for Cluster (%s) conflicted with another change, retrying in %s", operation, clusterID, delay)
+		time.Sleep(delay)
 	}
-	return nil
 }
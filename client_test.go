@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/mutexkv"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func testClient(handler http.HandlerFunc) (*InstaclustrClient, func()) {
+	minDelay, maxDelay, pollInterval, maintenanceInterval := retryMinDelay, retryMaxDelay, waitPollInterval, maintenancePollInterval
+	retryMinDelay, retryMaxDelay = time.Millisecond, 5*time.Millisecond
+	waitPollInterval, maintenancePollInterval = time.Millisecond, time.Millisecond
+	server := httptest.NewServer(handler)
+	client := &InstaclustrClient{
+		config:        Config{URL: server.URL, MaxRetries: 3},
+		client:        &http.Client{},
+		firewallLocks: mutexkv.NewMutexKV(),
+	}
+	return client, func() {
+		server.Close()
+		retryMinDelay, retryMaxDelay = minDelay, maxDelay
+		waitPollInterval, maintenancePollInterval = pollInterval, maintenanceInterval
+	}
+}
+
+// testSequenceHandler answers GET requests with each of bodies in turn,
+// repeating the last, and an empty body with a 404. Other requests are passed
+// to other. gets counts the GET requests answered.
+func testSequenceHandler(bodies []string, gets *int, other http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			other(w, r)
+			return
+		}
+		body := bodies[len(bodies)-1]
+		if *gets < len(bodies) {
+			body = bodies[*gets]
+		}
+		*gets++
+		if body == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}
+}
+
+// testDeleteCase is a delete answered with DeleteStatus, then polled with
+// each of Gets in turn as for testSequenceHandler
+type testDeleteCase struct {
+	DeleteStatus int
+	Gets         []string
+}
+
+// testResourceDelete deletes the resource for each case and checks it is only
+// removed from state once every poll response has been served
+func testResourceDelete(t *testing.T, r *schema.Resource, raw map[string]interface{}, id string, cases map[string]testDeleteCase) {
+	for name, tc := range cases {
+		gets := 0
+		client, done := testClient(testSequenceHandler(tc.Gets, &gets, func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(tc.DeleteStatus)
+		}))
+		d := schema.TestResourceDataRaw(t, r.Schema, raw)
+		d.SetId(id)
+		err := r.Delete(d, client)
+		done()
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if d.Id() != "" {
+			t.Fatalf("%s: expected resource to be removed from state", name)
+		}
+		if gets < len(tc.Gets) {
+			t.Fatalf("%s: expected the delete to be polled until done, got %d polls", name, gets)
+		}
+	}
+}
+
+// testResourceDeleteError checks that a failed delete is returned and keeps
+// the resource in state
+func testResourceDeleteError(t *testing.T, r *schema.Resource, raw map[string]interface{}, id string) {
+	client, done := testClient(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"bad request"}`))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, r.Schema, raw)
+	d.SetId(id)
+	err := r.Delete(d, client)
+	if err == nil || !strings.Contains(err.Error(), "bad request") {
+		t.Fatalf("expected delete error, got %v", err)
+	}
+	if d.Id() != id {
+		t.Fatalf("expected resource to stay in state after a failed delete")
+	}
+}
+
+func TestClientRetry_transientGet(t *testing.T) {
+	attempts := 0
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer done()
+
+	response, err := client.doGet("cluster")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || attempts != 3 {
+		t.Fatalf("expected 200 after 3 attempts, got %d after %d", response.StatusCode, attempts)
+	}
+}
+
+func TestClientRetry_exhausted(t *testing.T) {
+	attempts := 0
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+	defer done()
+
+	response, err := client.doGet("cluster")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusGatewayTimeout || attempts != 4 {
+		t.Fatalf("expected 504 after 4 attempts, got %d after %d", response.StatusCode, attempts)
+	}
+}
+
+func TestClientRetry_timeout(t *testing.T) {
+	var attempts int32
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	defer done()
+	client.client.Timeout = 10 * time.Millisecond
+
+	if _, err := client.doGet("cluster"); err == nil {
+		t.Fatal("expected a request with no response to time out")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 4 {
+		t.Fatalf("expected timed out GET to be retried, got %d attempts", n)
+	}
+}
+
+func TestClientRetry_postOnlyWhenThrottled(t *testing.T) {
+	attempts := 0
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer done()
+
+	response, err := client.doPost("", []byte("{}"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	response.Body.Close()
+	if attempts != 1 {
+		t.Fatalf("expected POST to not be retried on 502, got %d attempts", attempts)
+	}
+}
+
+func TestClientRetry_postThrottledResendsBody(t *testing.T) {
+	attempts := 0
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		buf := make([]byte, 2)
+		if n, _ := r.Body.Read(buf); n != 2 || string(buf) != "{}" {
+			t.Errorf("attempt %d: unexpected body %q", attempts, buf[:n])
+		}
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	defer done()
+
+	response, err := client.doPost("", []byte("{}"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusAccepted || attempts != 2 {
+		t.Fatalf("expected 202 after 2 attempts, got %d after %d", response.StatusCode, attempts)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if delay, ok := parseRetryAfter("7"); !ok || delay != 7*time.Second {
+		t.Fatalf("expected 7s, got %s (%t)", delay, ok)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(date); !ok || delay <= 0 || delay > time.Minute {
+		t.Fatalf("expected delay up to 1m, got %s (%t)", delay, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Fatal("expected invalid Retry-After to be ignored")
+	}
+}
+
+func TestCheckResponse_apiError(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1234")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","message":"Cluster not found"}`))
+	})
+	defer done()
+
+	_, err := client.ClusterClient().Get("missing")
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found error, got %#v", err)
+	}
+	apiErr := err.(*APIError)
+	if apiErr.Method != http.MethodGet || apiErr.Path != "/missing" || apiErr.RequestID != "req-1234" || apiErr.Message != "Cluster not found" {
+		t.Fatalf("unexpected error fields: %#v", apiErr)
+	}
+}
+
+func TestIsNotFound_otherErrors(t *testing.T) {
+	if IsNotFound(&APIError{StatusCode: http.StatusInternalServerError}) {
+		t.Fatal("expected 500 to not be a not found error")
+	}
+	if IsNotFound(fmt.Errorf("connection reset")) {
+		t.Fatal("expected untyped error to not be a not found error")
+	}
+}
+
+func TestFirewallClient_serializesPerCluster(t *testing.T) {
+	var mu sync.Mutex
+	inFlight := map[string]int{}
+	maxInFlight := 0
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight[r.URL.Path]++
+		if inFlight[r.URL.Path] > maxInFlight {
+			maxInFlight = inFlight[r.URL.Path]
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight[r.URL.Path]--
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	defer done()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := client.FirewallClient().Create("cluster", fmt.Sprintf("10.%d.0.0/16", i), []string{"CASSANDRA"})
+			if err != nil {
+				t.Errorf("err: %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if maxInFlight != 1 {
+		t.Fatalf("expected firewall changes to one cluster to be serialized, got %d in flight", maxInFlight)
+	}
+}
+
+func TestFirewallClient_retriesConflict(t *testing.T) {
+	attempts := 0
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	defer done()
+
+	err := client.FirewallClient().Create("cluster", "10.1.0.0/16", []string{"CASSANDRA"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
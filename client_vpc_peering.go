@@ -2,7 +2,6 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"strings"
 )
@@ -22,6 +21,7 @@ type VpcPeer struct {
 	PeerAccountID       string `json:"peerAccountId"`
 	PeerSubnet          string `json:"peerSubnet"`
 	StatusCode          string `json:"statusCode"`
+	StatusMessage       string `json:"statusMessage"`
 }
 
 // CreateVpcPeerRequest is the object for creating a new VPC peering request
@@ -44,8 +44,9 @@ func (c *VpcPeeringClient) List(clusterDatacenterID string) ([]*VpcPeer, error)
 	}
 	defer response.Body.Close()
 	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return nil, fmt.Errorf("List VPC Peering Connection did not return 200/202 [%d]:\n%s", response.StatusCode, string(responseData))
+	err = checkResponse("List VPC Peering Connection", response, responseData)
+	if err != nil {
+		return nil, err
 	}
 	vpcPeers := []*VpcPeer{}
 	err = json.Unmarshal(responseData, &vpcPeers)
@@ -63,8 +64,9 @@ func (c *VpcPeeringClient) Get(clusterDatacenterID, vpcPeeringConnectionID strin
 	}
 	defer response.Body.Close()
 	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return nil, fmt.Errorf("Get VPC Peering Connection did not return 200/202 [%d]:\n%s", response.StatusCode, string(responseData))
+	err = checkResponse("Get VPC Peering Connection", response, responseData)
+	if err != nil {
+		return nil, err
 	}
 	vpcPeer := &VpcPeer{}
 	err = json.Unmarshal(responseData, vpcPeer)
@@ -86,8 +88,9 @@ func (c *VpcPeeringClient) Create(clusterDatacenterID string, request *CreateVpc
 	}
 	defer response.Body.Close()
 	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return nil, fmt.Errorf("Create VPC Peering Connection did not return 200/202 [%d]:\n%s\n%s\n%s", response.StatusCode, string(responseData), string(bytes), "ID: "+clusterDatacenterID)
+	err = checkResponse("Create VPC Peering Connection", response, responseData)
+	if err != nil {
+		return nil, err
 	}
 	createResponse := &CreateVpcPeerResponse{}
 	err = json.Unmarshal(responseData, createResponse)
@@ -105,8 +108,5 @@ func (c *VpcPeeringClient) Delete(clusterDatacenterID, vpcPeeringConnectionID st
 	}
 	defer response.Body.Close()
 	responseData, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode != 200 && response.StatusCode != 202 {
-		return fmt.Errorf("Delete VPC Peering Connection did not return 200/202 [%d]:\n%s", response.StatusCode, string(responseData))
-	}
-	return nil
+	return checkResponse("Delete VPC Peering Connection", response, responseData)
 }
@@ -1,8 +1,13 @@
 package main
 
+import "time"
+
 // Config is the configuration for talking to the Instaclustr API
 type Config struct {
-	AccessKey string
-	SecretKey string
-	URL       string
+	AccessKey      string
+	SecretKey      string
+	URL            string
+	MaxRetries     int
+	RequestTimeout time.Duration
+	DefaultTags    map[string]string
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceInstaclustrClusterCertificates() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceInstaclustrClusterCertificatesRead,
+
+		Schema: map[string]*schema.Schema{
+			"cluster_id": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateUUID,
+			},
+			"ca_certificate": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"truststore_base64": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceInstaclustrClusterCertificatesRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*InstaclustrClient).ClusterClient()
+	clusterID := d.Get("cluster_id").(string)
+	cluster, err := client.Get(clusterID)
+	if err != nil {
+		return err
+	}
+	if cluster.ClusterCertificateDownload == "" || cluster.ClusterCertificateDownload == "disabled" {
+		return fmt.Errorf("Cluster (%s) has no certificates available to download", clusterID)
+	}
+	bundle, err := client.DownloadCertificates(cluster.ClusterCertificateDownload)
+	if err != nil {
+		return err
+	}
+	caCertificate, truststore, err := parseCertificateBundle(bundle)
+	if err != nil {
+		return fmt.Errorf("Error reading certificates for Cluster (%s): %s", clusterID, err)
+	}
+
+	d.SetId(clusterID)
+	d.Set("ca_certificate", caCertificate)
+	d.Set("truststore_base64", base64.StdEncoding.EncodeToString(truststore))
+	return nil
+}
+
+// parseCertificateBundle extracts the CA certificate PEM and the Java
+// truststore from a certificate bundle. The bundle is a zip archive, or the
+// CA certificate PEM on its own.
+func parseCertificateBundle(bundle []byte) (string, []byte, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(bundle), []byte("-----BEGIN CERTIFICATE-----")) {
+		return string(bundle), nil, nil
+	}
+	archive, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		return "", nil, fmt.Errorf("certificate bundle is neither a zip archive nor a PEM certificate: %s", err)
+	}
+	var caCertificate string
+	var truststore []byte
+	for _, f := range archive.File {
+		name := strings.ToLower(path.Base(f.Name))
+		isCertificate := strings.HasSuffix(name, ".pem") || strings.HasSuffix(name, ".crt")
+		isTruststore := strings.HasSuffix(name, ".jks") && strings.Contains(name, "truststore")
+		if !isCertificate && !isTruststore {
+			continue
+		}
+		contents, err := readZipFile(f)
+		if err != nil {
+			return "", nil, err
+		}
+		// Prefer the CA certificate when the bundle holds several certificates
+		if isCertificate && (caCertificate == "" || strings.Contains(name, "ca")) {
+			caCertificate = string(contents)
+		}
+		if isTruststore {
+			truststore = contents
+		}
+	}
+	if caCertificate == "" {
+		return "", nil, fmt.Errorf("certificate bundle has no CA certificate")
+	}
+	return caCertificate, truststore, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	reader, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
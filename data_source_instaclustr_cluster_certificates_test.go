@@ -0,0 +1,87 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+const testCACertificate = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+func TestDataSourceInstaclustrClusterCertificatesRead(t *testing.T) {
+	bundle := testCertificateBundle(t, map[string]string{
+		"certificates/cluster-ca-certificate.pem": testCACertificate,
+		"certificates/truststore.jks":             "jks",
+		"certificates/README.md":                  "readme",
+	})
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cluster-id/certificates" {
+			if r.Header.Get("Authorization") == "" {
+				t.Errorf("expected certificate download to be authenticated")
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(bundle)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(fmt.Sprintf(`{"id":"cluster-id","clusterCertificateDownload":"http://%s/cluster-id/certificates"}`, r.Host)))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, dataSourceInstaclustrClusterCertificates().Schema, map[string]interface{}{"cluster_id": "cluster-id"})
+	if err := dataSourceInstaclustrClusterCertificatesRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ca := d.Get("ca_certificate").(string); ca != testCACertificate {
+		t.Fatalf("expected CA certificate, got %q", ca)
+	}
+	if truststore := d.Get("truststore_base64").(string); truststore != base64.StdEncoding.EncodeToString([]byte("jks")) {
+		t.Fatalf("expected base64 truststore, got %q", truststore)
+	}
+}
+
+func TestDataSourceInstaclustrClusterCertificatesRead_foreignURL(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterCertificateDownload":"https://example.com/certificates"}`))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, dataSourceInstaclustrClusterCertificates().Schema, map[string]interface{}{"cluster_id": "cluster-id"})
+	err := dataSourceInstaclustrClusterCertificatesRead(d, client)
+	if err == nil || !strings.Contains(err.Error(), "is not on the configured API") {
+		t.Fatalf("expected foreign download URL to be rejected, got %v", err)
+	}
+}
+
+func TestParseCertificateBundle_pem(t *testing.T) {
+	ca, truststore, err := parseCertificateBundle([]byte(testCACertificate))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ca != testCACertificate || truststore != nil {
+		t.Fatalf("expected PEM bundle to be the CA certificate, got %q, %q", ca, truststore)
+	}
+}
+
+func testCertificateBundle(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, contents := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		f.Write([]byte(contents))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return buf.Bytes()
+}
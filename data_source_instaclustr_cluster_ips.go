@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/hashicorp/terraform/helper/schema"
 )
@@ -12,9 +13,10 @@ func dataSourceInstaclustrClusterIPs() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"cluster_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateUUID,
 			},
 			"public_ips": &schema.Schema{
 				Type:     schema.TypeList,
@@ -40,23 +42,44 @@ func dataSourceInstaclustrClusterIPsRead(d *schema.ResourceData, m interface{})
 	if err != nil {
 		return err
 	}
+	switch cluster.ClusterStatus {
+	case "DELETING", "DELETED":
+		return fmt.Errorf("Cluster (%s) is %s", cluster.ID, cluster.ClusterStatus)
+	case "FAILED":
+		log.Printf("[WARN] Cluster (%s) is FAILED, its IPs may be incomplete", cluster.ID)
+	}
 	publicIps, privateIps := ipsForCluster(cluster)
 
 	d.SetId(d.Get("cluster_id").(string))
-	d.Set("cidr_block", fmt.Sprintf("%s", cluster.Datacenters[0].CdcNetwork))
+	d.Set("cidr_block", clusterCidrBlock(cluster))
 	d.Set("public_ips", publicIps)
 	d.Set("private_ips", privateIps)
 
 	return nil
 }
 
+// clusterCidrBlock returns the network of the cluster's first datacenter,
+// falling back to the cluster network before any datacenter is listed
+func clusterCidrBlock(cluster *ClusterStatus) string {
+	if len(cluster.Datacenters) > 0 && cluster.Datacenters[0].CdcNetwork != "" {
+		return cluster.Datacenters[0].CdcNetwork
+	}
+	if cluster.ClusterNetwork.Network != "" {
+		return fmt.Sprintf("%s/%d", cluster.ClusterNetwork.Network, cluster.ClusterNetwork.PrefixLength)
+	}
+	return ""
+}
+
 func ipsForCluster(cluster *ClusterStatus) (publicIps []string, privateIps []string) {
 	publicIps = []string{}
 	privateIps = []string{}
 
 	for _, datacenter := range cluster.Datacenters {
 		for _, node := range datacenter.Nodes {
-			privateIps = append(privateIps, node.PrivateAddress)
+			// Nodes still provisioning have no addresses yet
+			if node.PrivateAddress != "" {
+				privateIps = append(privateIps, node.PrivateAddress)
+			}
 			if node.PublicAddress != "" {
 				publicIps = append(publicIps, node.PublicAddress)
 			}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 )
 
 func TestAccInstaclustrClusterIpsDatasource_basic(t *testing.T) {
@@ -21,6 +24,39 @@ func TestAccInstaclustrClusterIpsDatasource_basic(t *testing.T) {
 	})
 }
 
+func TestDataSourceInstaclustrClusterIPsRead_provisioning(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterStatus":"GENESIS","clusterNetwork":{"network":"10.0.0.0","prefixLength":16},"dataCentres":[]}`))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, dataSourceInstaclustrClusterIPs().Schema, map[string]interface{}{"cluster_id": "cluster-id"})
+	if err := dataSourceInstaclustrClusterIPsRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if cidr := d.Get("cidr_block").(string); cidr != "10.0.0.0/16" {
+		t.Fatalf("expected cluster network as cidr_block, got %q", cidr)
+	}
+	if ips := d.Get("private_ips").([]interface{}); len(ips) != 0 {
+		t.Fatalf("expected no private IPs, got %v", ips)
+	}
+}
+
+func TestDataSourceInstaclustrClusterIPsRead_deleted(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterStatus":"DELETED"}`))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, dataSourceInstaclustrClusterIPs().Schema, map[string]interface{}{"cluster_id": "cluster-id"})
+	err := dataSourceInstaclustrClusterIPsRead(d, client)
+	if err == nil || !strings.Contains(err.Error(), "is DELETED") {
+		t.Fatalf("expected deleted cluster error, got %v", err)
+	}
+}
+
 const testAccInstaclustrClusterIpsDatasourceConfig = `
 resource "instaclustr_cluster" "foo" {
   name = "terraform-test-acc"
@@ -2,7 +2,9 @@ package main
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/hashicorp/terraform/helper/mutexkv"
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
@@ -27,12 +29,34 @@ func Provider() *schema.Provider {
 				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("INSTACLUSTR_URL", "https://api.instaclustr.com/provisioning/v1"),
 			},
+			"max_retries": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      5,
+				ValidateFunc: validateIntAtLeast(0),
+				Description:  "Maximum number of times a throttled or failed api request is retried",
+			},
+			"request_timeout": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      60,
+				ValidateFunc: validateIntAtLeast(1),
+				Description:  "Seconds to wait for an api request before it is treated as a failure and retried",
+			},
+			"default_tags": &schema.Schema{
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Tags applied to every cluster, overridden by the cluster's own tags",
+			},
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"instaclustr_cluster_ips": dataSourceInstaclustrClusterIPs(),
+			"instaclustr_cluster_ips":          dataSourceInstaclustrClusterIPs(),
+			"instaclustr_cluster_certificates": dataSourceInstaclustrClusterCertificates(),
 		},
 		ResourcesMap: map[string]*schema.Resource{
 			"instaclustr_cluster":                resourceCluster(),
+			"instaclustr_cluster_firewall":       resourceClusterFirewall(),
 			"instaclustr_firewall_rule":          resourceFirewallRule(),
 			"instaclustr_vpc_peering_connection": resourceVpcPeeringConnection(),
 		},
@@ -43,13 +67,20 @@ func Provider() *schema.Provider {
 func configureProvider(d *schema.ResourceData) (interface{}, error) {
 
 	config := Config{
-		AccessKey: d.Get("access_key").(string),
-		SecretKey: d.Get("secret_key").(string),
-		URL:       d.Get("url").(string),
+		AccessKey:      d.Get("access_key").(string),
+		SecretKey:      d.Get("secret_key").(string),
+		URL:            d.Get("url").(string),
+		MaxRetries:     d.Get("max_retries").(int),
+		RequestTimeout: time.Duration(d.Get("request_timeout").(int)) * time.Second,
+		DefaultTags:    map[string]string{},
+	}
+	for k, v := range d.Get("default_tags").(map[string]interface{}) {
+		config.DefaultTags[k] = v.(string)
 	}
 
 	return &InstaclustrClient{
-		config: config,
-		client: &http.Client{},
+		config:        config,
+		client:        &http.Client{Timeout: config.RequestTimeout},
+		firewallLocks: mutexkv.NewMutexKV(),
 	}, nil
 }
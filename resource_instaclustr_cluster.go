@@ -2,21 +2,80 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"log"
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform/helper/hashcode"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
+// datacenterImmutableFields are the datacenter arguments that can't be changed
+// on an existing datacenter without replacing the cluster
+var datacenterImmutableFields = []string{
+	"name",
+	"provider_name",
+	"account",
+	"region",
+	"auth",
+	"client_encryption",
+	"disk_encryption_key",
+	"use_private_rpc_broadcast_address",
+	"default_network",
+}
+
+var (
+	// clusterFailureStates are the cluster statuses that end a wait with an error
+	clusterFailureStates = []string{"FAILED", "DELETING", "DELETED"}
+	// nodeFailureStates are the node statuses that end a wait with an error
+	nodeFailureStates = []string{"FAILED"}
+	// clusterWaitModes are the values accepted by the wait_for argument
+	clusterWaitModes = []string{"none", "cluster_running", "all_nodes_running"}
+	// waitPollInterval is how often a wait checks the API until it completes
+	waitPollInterval = 3 * time.Second
+	// maintenancePollInterval is how often a wait for a resize or upgrade
+	// checks the API until it completes
+	maintenancePollInterval = 10 * time.Second
+)
+
+var (
+	// privateNetworks are the RFC 1918 ranges a datacenter's default_network must fall within
+	privateNetworks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+	// clusterNetworkMinPrefix and clusterNetworkMaxPrefix bound the prefix
+	// length Instaclustr accepts for a datacenter's default_network
+	clusterNetworkMinPrefix = 12
+	clusterNetworkMaxPrefix = 22
+	// providerSizePatterns recognise node sizes that belong to a provider
+	providerSizePatterns = map[string]*regexp.Regexp{
+		"AWS_VPC": regexp.MustCompile(`^[a-z][0-9][a-z]*\.[0-9]*(nano|micro|small|medium|large|xlarge)`),
+		"AZURE":   regexp.MustCompile(`^(Standard|Basic)_`),
+		"GCP":     regexp.MustCompile(`^[a-z][0-9][a-z]?-(standard|highmem|highcpu|megamem|ultramem)-`),
+	}
+)
+
 func resourceCluster() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceInstaclustrClusterCreate,
 		Read:   resourceInstaclustrClusterRead,
+		Update: resourceInstaclustrClusterUpdate,
 		Delete: resourceInstaclustrClusterDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: resourceInstaclustrClusterImport,
+		},
+		CustomizeDiff: resourceInstaclustrClusterCustomizeDiff,
+		SchemaVersion: 1,
+		MigrateState:  resourceInstaclustrClusterMigrateState,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(15 * time.Minute),
+			Update: schema.DefaultTimeout(30 * time.Minute),
+			Delete: schema.DefaultTimeout(15 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -28,7 +87,40 @@ func resourceCluster() *schema.Resource {
 			"version": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
+			},
+			"wait_for": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "cluster_running",
+				ValidateFunc: stringInList(clusterWaitModes),
+			},
+			"deletion_protection": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"tags": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"tags_all": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"default_username": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"default_password": &schema.Schema{
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"certificate_download_url": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
 			},
 			"public_ips": &schema.Schema{
 				Type:     schema.TypeList,
@@ -43,31 +135,36 @@ func resourceCluster() *schema.Resource {
 			"datacenter": &schema.Schema{
 				Type:     schema.TypeList,
 				Required: true,
-				ForceNew: true,
 				MinItems: 1,
-				MaxItems: 1,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
+						"name": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
 						"provider_name": &schema.Schema{
 							Type:         schema.TypeString,
 							Required:     true,
-							ForceNew:     true,
 							ValidateFunc: stringInList([]string{"AWS_VPC", "AZURE", "SOFTLAYER_BARE_METAL", "GCP"}),
 						},
 						"account": &schema.Schema{
 							Type:     schema.TypeString,
 							Optional: true,
-							ForceNew: true,
+							Computed: true,
 						},
 						"region": &schema.Schema{
 							Type:     schema.TypeString,
 							Required: true,
-							ForceNew: true,
 						},
 						"size": &schema.Schema{
 							Type:     schema.TypeString,
 							Required: true,
-							ForceNew: true,
+						},
+						"concurrent_resizes": &schema.Schema{
+							Type:     schema.TypeInt,
+							Optional: true,
+							Default:  1,
 						},
 						"datacenter_id": &schema.Schema{
 							Type:     schema.TypeString,
@@ -77,47 +174,53 @@ func resourceCluster() *schema.Resource {
 							Type:     schema.TypeBool,
 							Optional: true,
 							Default:  false,
-							ForceNew: true,
 						},
 						"client_encryption": &schema.Schema{
 							Type:     schema.TypeBool,
+							Optional: true,
 							Computed: true,
 						},
 						"disk_encryption_key": &schema.Schema{
-							Type:     schema.TypeString,
-							Optional: true,
-							ForceNew: true,
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validateKmsKey,
+							DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+								return equivalentKmsKeys(old, new)
+							},
 						},
 						"use_private_rpc_broadcast_address": &schema.Schema{
 							Type:     schema.TypeBool,
 							Optional: true,
 							Default:  true,
-							ForceNew: true,
 						},
 						"default_network": &schema.Schema{
-							Type:     schema.TypeString,
-							Required: true,
-							ForceNew: true,
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateCIDR,
+						},
+						"initial_firewall_rules": &schema.Schema{
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validateCIDR,
+							},
 						},
 						"rack": &schema.Schema{
 							Type:     schema.TypeSet,
 							Required: true,
-							ForceNew: true,
 							MinItems: 2,
-							Elem: &schema.Resource{
-								Schema: map[string]*schema.Schema{
-									"name": &schema.Schema{
-										Type:     schema.TypeString,
-										Required: true,
-										ForceNew: true,
-									},
-									"node_count": &schema.Schema{
-										Type:     schema.TypeInt,
-										Required: true,
-										ForceNew: true,
-									},
-								},
-							},
+							Elem:     rackResource(),
+						},
+						"public_ips": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"private_ips": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
 						},
 					},
 				},
@@ -128,83 +231,591 @@ func resourceCluster() *schema.Resource {
 
 func resourceInstaclustrClusterCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*InstaclustrClient).ClusterClient()
-	datacenter := d.Get("datacenter").([]interface{})[0].(map[string]interface{})
+	datacenters := d.Get("datacenter").([]interface{})
+	datacenter := datacenters[0].(map[string]interface{})
 	request := CreateClusterRequest{
 		ClusterName: d.Get("name").(string),
 		Version:     d.Get("version").(string),
 
 		Provider: datacenter["provider_name"].(string),
 		Size:     datacenter["size"].(string),
-		Region: CreateClusterRequestRegion{
-			Datacenter:                    datacenter["region"].(string),
-			UsePrivateBroadcastRPCAddress: datacenter["use_private_rpc_broadcast_address"].(bool),
-			DefaultNetwork:                datacenter["default_network"].(string),
-			AuthnAuthz:                    datacenter["auth"].(bool),
-			ClientEncryption:              false,
-			RackAllocations:               []CreateClusterRequestRegionRackAllocation{},
-			FirewallRules:                 []string{},
-		},
+		Region:   expandDatacenterRegion(datacenter, datacenterClientEncryption(d, 0)),
+		Tags:     mergeTags(defaultTags(m), expandTags(d.Get("tags").(map[string]interface{}))),
 	}
 	if account, ok := datacenter["account"]; ok {
 		request.Account = account.(string)
 	}
-	if key, ok := datacenter["disk_encryption_key"]; ok && key != "" {
-		request.Region.ClientEncryption = true
-		request.Region.DiskEncryptionKey = key.(string)
-	}
-
-	for _, rack := range datacenter["rack"].(*schema.Set).List() {
-		alloc := rack.(map[string]interface{})
-		request.Region.RackAllocations = append(request.Region.RackAllocations, CreateClusterRequestRegionRackAllocation{
-			Name:      alloc["name"].(string),
-			NodeCount: alloc["node_count"].(int),
-		})
-	}
 	response, err := client.Create(request)
 	if err != nil {
 		return err
 	}
-	stateConf := &resource.StateChangeConf{
-		Pending:    []string{"RUNNING", "GENESIS", "PROVISIONING", "PROVISIONED"},
-		Target:     []string{"RUNNING"},
-		Refresh:    clusterStateRefreshFunc(client, response.ID),
-		Timeout:    15 * time.Minute,
-		Delay:      3 * time.Second,
-		MinTimeout: 3 * time.Second,
+	// Set the ID before waiting so a failed wait taints the cluster
+	d.SetId(response.ID)
+	waitFor := d.Get("wait_for").(string)
+	// Datacenters can only be added to a running cluster, whatever wait_for says
+	if waitFor != "none" || len(datacenters) > 1 {
+		err = waitForClusterRunning(client, response.ID, d.Timeout(schema.TimeoutCreate))
+		if err != nil {
+			return err
+		}
 	}
-	_, waitErr := stateConf.WaitForState()
-	if waitErr != nil {
-		return fmt.Errorf(
-			"Error waiting for Cluster (%s) to be Running: %s", response.ID, waitErr)
+	for i, dc := range datacenters[1:] {
+		err = addDatacenter(client, response.ID, dc.(map[string]interface{}), datacenterClientEncryption(d, i+1), d.Timeout(schema.TimeoutCreate))
+		if err != nil {
+			return err
+		}
+	}
+	if waitFor == "all_nodes_running" {
+		err = waitForNodesRunning(client, response.ID, datacenterNodeCount(datacenters), d.Timeout(schema.TimeoutCreate))
+		if err != nil {
+			return err
+		}
 	}
-	d.SetId(response.ID)
 	return resourceInstaclustrClusterRead(d, m)
 }
 
+func rackResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"node_count": &schema.Schema{
+				Type:     schema.TypeInt,
+				Required: true,
+			},
+		},
+	}
+}
+
+// resourceInstaclustrClusterImport sets the arguments Read can't recover from
+// the API to their defaults, Read builds everything else
+func resourceInstaclustrClusterImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	d.Set("wait_for", "cluster_running")
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceInstaclustrClusterRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*InstaclustrClient).ClusterClient()
 	cluster, err := client.Get(d.Id())
 	if err != nil {
-		d.SetId("")
+		if IsNotFound(err) {
+			log.Printf("[WARN] Cluster (%s) not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
 		return err
 	}
+	switch cluster.ClusterStatus {
+	case "DELETING", "DELETED":
+		log.Printf("[WARN] Cluster (%s) is %s, removing from state", d.Id(), cluster.ClusterStatus)
+		d.SetId("")
+		return nil
+	case "FAILED":
+		log.Printf("[WARN] Cluster (%s) is FAILED, it may need to be replaced", d.Id())
+	}
 
-	//Set non-Computed values first
 	d.Set("name", cluster.ClusterName)
 	d.Set("version", cluster.CassandraVersion)
+	// deletion_protection isn't stored by the API, record it so imported and
+	// migrated clusters don't show a diff for the default
+	d.Set("deletion_protection", d.Get("deletion_protection").(bool))
+	d.Set("tags", resourceTags(cluster.Tags, defaultTags(m), d.Get("tags").(map[string]interface{})))
+	d.Set("tags_all", cluster.Tags)
+	d.Set("default_username", cluster.Username)
+	d.Set("default_password", cluster.InstaclustrUserPassword)
+	d.Set("certificate_download_url", cluster.ClusterCertificateDownload)
+
+	// Datacenters aren't listed until the cluster leaves GENESIS, keep the
+	// configured ones until then
+	if len(cluster.Datacenters) > 0 {
+		err = d.Set("datacenter", flattenDatacenters(cluster, d.Get("datacenter").([]interface{})))
+		if err != nil {
+			return fmt.Errorf("Error setting datacenter for Cluster (%s): %s", d.Id(), err)
+		}
+	} else {
+		log.Printf("[DEBUG] Cluster (%s) is %s and has no datacenters yet", d.Id(), cluster.ClusterStatus)
+	}
+
+	publicIps, privateIps := ipsForCluster(cluster)
+	d.Set("public_ips", publicIps)
+	d.Set("private_ips", privateIps)
+	return nil
+}
+
+func resourceInstaclustrClusterUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*InstaclustrClient).ClusterClient()
+	if d.HasChange("version") {
+		err := upgradeCluster(client, d.Id(), d.Get("version").(string), d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return err
+		}
+	}
+	if d.HasChange("tags_all") {
+		err := client.UpdateTags(d.Id(), expandTags(d.Get("tags_all").(map[string]interface{})))
+		if err != nil {
+			return err
+		}
+	}
+	if d.HasChange("datacenter") {
+		o, n := d.GetChange("datacenter")
+		existing := len(o.([]interface{}))
+		for i := 0; i < existing; i++ {
+			err := updateDatacenter(d, m, i)
+			if err != nil {
+				return err
+			}
+		}
+		for i, dc := range n.([]interface{})[existing:] {
+			err := addDatacenter(client, d.Id(), dc.(map[string]interface{}), datacenterClientEncryption(d, existing+i), d.Timeout(schema.TimeoutUpdate))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return resourceInstaclustrClusterRead(d, m)
+}
+
+// updateDatacenter applies the in place changes to an existing datacenter,
+// adding nodes before resizing so the new nodes are resized too
+func updateDatacenter(d *schema.ResourceData, m interface{}, index int) error {
+	client := m.(*InstaclustrClient).ClusterClient()
+	prefix := fmt.Sprintf("datacenter.%d", index)
+	datacenterID := d.Get(prefix + ".datacenter_id").(string)
+	if d.HasChange(prefix + ".rack") {
+		oldRacks, newRacks := d.GetChange(prefix + ".rack")
+		err := addNodes(client, d.Id(), datacenterID, rackNodeCounts(oldRacks.(*schema.Set)), rackNodeCounts(newRacks.(*schema.Set)), d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return err
+		}
+	}
+	if d.HasChange(prefix + ".size") {
+		err := resizeDatacenter(client, d.Id(), datacenterID, d.Get(prefix+".size").(string), d.Get(prefix+".concurrent_resizes").(int), d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resourceInstaclustrClusterDelete(d *schema.ResourceData, m interface{}) error {
+	if d.Get("deletion_protection").(bool) {
+		return fmt.Errorf("Cluster (%s) has deletion_protection enabled, apply deletion_protection = false before destroying it", d.Id())
+	}
+	client := m.(*InstaclustrClient).ClusterClient()
+	err := client.Delete(d.Id())
+	if err != nil {
+		if IsNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"RUNNING", "GENESIS", "PROVISIONING", "PROVISIONED", "DEFERRED", "FAILED", "DELETING"},
+		Target:     []string{"DELETED"},
+		Refresh:    clusterDeleteStateRefreshFunc(client, d.Id()),
+		Timeout:    d.Timeout(schema.TimeoutDelete),
+		Delay:      waitPollInterval,
+		MinTimeout: waitPollInterval,
+	}
+	_, waitErr := stateConf.WaitForState()
+	if waitErr != nil {
+		return fmt.Errorf(
+			"Error waiting for Cluster (%s) to be Deleted: %s", d.Id(), waitErr)
+	}
+	d.SetId("")
+	return nil
+}
+
+// resourceInstaclustrClusterCustomizeDiff validates the datacenters and merges
+// the default tags into tags_all. It allows the version to be upgraded,
+// datacenters to be appended, racks to be scaled up and nodes to be resized in
+// place, while any other change to an existing datacenter replaces the cluster.
+// Replacing a cluster with deletion_protection enabled is rejected.
+func resourceInstaclustrClusterCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" || d.HasChange("datacenter") {
+		for i, v := range d.Get("datacenter").([]interface{}) {
+			dc, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key := fmt.Sprintf("datacenter.%d", i)
+			if err := validateDatacenter(key, dc); err != nil {
+				return err
+			}
+			diskEncryptionKey, _ := dc["disk_encryption_key"].(string)
+			if _, ok := d.GetOkExists(key + ".client_encryption"); !ok && diskEncryptionKey != "" {
+				log.Printf("[WARN] %s.disk_encryption_key is set without client_encryption, which enables client encryption. "+
+					"This is deprecated, set client_encryption explicitly", key)
+			}
+		}
+	}
+	tags := mergeTags(defaultTags(m), expandTags(d.Get("tags").(map[string]interface{})))
+	if !reflect.DeepEqual(tags, expandTags(d.Get("tags_all").(map[string]interface{}))) {
+		if err := d.SetNew("tags_all", tags); err != nil {
+			return err
+		}
+	}
+	if d.Id() == "" {
+		return nil
+	}
+	replaced, err := forceNewClusterChanges(d)
+	if err != nil {
+		return err
+	}
+	if d.HasChange("name") {
+		replaced = append(replaced, "name")
+	}
+	if len(replaced) == 0 {
+		return nil
+	}
+	log.Printf("[WARN] Cluster (%s) will be replaced and its data lost because of changes to: %s", d.Id(), strings.Join(replaced, ", "))
+	if o, n := d.GetChange("deletion_protection"); o.(bool) || n.(bool) {
+		return fmt.Errorf("Cluster (%s) has deletion_protection enabled and changes to %s would replace it, "+
+			"apply deletion_protection = false first if the cluster should be replaced", d.Id(), strings.Join(replaced, ", "))
+	}
+	return nil
+}
+
+// forceNewClusterChanges marks the changes that can't be applied in place as
+// forcing a new cluster and returns their keys
+func forceNewClusterChanges(d *schema.ResourceDiff) ([]string, error) {
+	replaced := []string{}
+	forceNew := func(key string) error {
+		replaced = append(replaced, key)
+		return d.ForceNew(key)
+	}
+	if d.HasChange("version") {
+		o, n := d.GetChange("version")
+		replace, err := validateVersionUpgrade(o.(string), n.(string))
+		if err != nil {
+			return nil, err
+		}
+		if replace {
+			if err := forceNew("version"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !d.HasChange("datacenter") {
+		return replaced, nil
+	}
+	o, n := d.GetChange("datacenter")
+	existing := len(o.([]interface{}))
+	if len(n.([]interface{})) < existing {
+		return replaced, forceNew("datacenter")
+	}
+	for i := 0; i < existing; i++ {
+		for _, field := range datacenterImmutableFields {
+			key := fmt.Sprintf("datacenter.%d.%s", i, field)
+			if field == "disk_encryption_key" {
+				if o, n := d.GetChange(key); equivalentKmsKeys(o.(string), n.(string)) {
+					continue
+				}
+			}
+			if d.HasChange(key) {
+				if err := forceNew(key); err != nil {
+					return nil, err
+				}
+			}
+		}
+		key := fmt.Sprintf("datacenter.%d.rack", i)
+		if !d.HasChange(key) {
+			continue
+		}
+		o, n := d.GetChange(key)
+		oldRacks, newRacks := rackNodeCounts(o.(*schema.Set)), rackNodeCounts(n.(*schema.Set))
+		if !sameRacks(oldRacks, newRacks) {
+			if err := forceNew(key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for name, count := range newRacks {
+			if count < oldRacks[name] {
+				return nil, fmt.Errorf("%s: cannot reduce node_count of rack %q from %d to %d, scaling down is not supported", key, name, oldRacks[name], count)
+			}
+		}
+	}
+	return replaced, nil
+}
+
+// validateDatacenter checks the datacenter arguments the API would otherwise
+// only reject once the cluster is being created. Values not yet known are skipped.
+func validateDatacenter(key string, dc map[string]interface{}) error {
+	if network, _ := dc["default_network"].(string); network != "" {
+		if err := validateClusterNetwork(network); err != nil {
+			return fmt.Errorf("%s.default_network: %s", key, err)
+		}
+	}
+	if racks, ok := dc["rack"].(*schema.Set); ok {
+		if racks.Len() < 2 {
+			return fmt.Errorf("%s.rack: at least 2 racks are required, got %d", key, racks.Len())
+		}
+		names := map[string]bool{}
+		for _, rack := range racks.List() {
+			name, _ := rack.(map[string]interface{})["name"].(string)
+			if name == "" {
+				continue
+			}
+			if names[name] {
+				return fmt.Errorf("%s.rack: rack name %q is used more than once", key, name)
+			}
+			names[name] = true
+		}
+	}
+	size, _ := dc["size"].(string)
+	if diskEncryptionKey, _ := dc["disk_encryption_key"].(string); diskEncryptionKey != "" && strings.HasPrefix(strings.ToLower(size), "t2.") {
+		return fmt.Errorf("%s.disk_encryption_key: disk encryption is not supported on t2 sizes, got size %q", key, size)
+	}
+	if providerName, _ := dc["provider_name"].(string); providerName != "" && size != "" {
+		for p, pattern := range providerSizePatterns {
+			if p != providerName && pattern.MatchString(size) {
+				return fmt.Errorf("%s.size: size %q is for %s and can't be used with provider_name %s", key, size, p, providerName)
+			}
+		}
+	}
+	return nil
+}
+
+// validateClusterNetwork checks that a network is a private IPv4 CIDR block
+// with a prefix length Instaclustr accepts
+func validateClusterNetwork(network string) error {
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil || ipNet.IP.To4() == nil {
+		return fmt.Errorf("%q is not a valid IPv4 CIDR block", network)
+	}
+	prefix, _ := ipNet.Mask.Size()
+	if prefix < clusterNetworkMinPrefix || prefix > clusterNetworkMaxPrefix {
+		return fmt.Errorf("%q must have a prefix length between /%d and /%d", network, clusterNetworkMinPrefix, clusterNetworkMaxPrefix)
+	}
+	for _, private := range privateNetworks {
+		_, privateNet, _ := net.ParseCIDR(private)
+		privatePrefix, _ := privateNet.Mask.Size()
+		if privateNet.Contains(ipNet.IP) && prefix >= privatePrefix {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q must be within a private network (%s)", network, strings.Join(privateNetworks, ", "))
+}
+
+// validateVersionUpgrade checks that a version change can be applied as an
+// in place upgrade. Versions of a different distribution require replacement,
+// while downgrades and upgrades skipping a major version are rejected.
+func validateVersionUpgrade(from, to string) (bool, error) {
+	fromName, fromVersion := splitClusterVersion(from)
+	toName, toVersion := splitClusterVersion(to)
+	if fromName != toName {
+		return true, nil
+	}
+	if fromVersion == nil || toVersion == nil {
+		return false, nil
+	}
+	if toVersion.LessThan(fromVersion) {
+		return false, fmt.Errorf("version: cannot downgrade cluster from %s to %s", from, to)
+	}
+	if toVersion.Segments()[0]-fromVersion.Segments()[0] > 1 {
+		return false, fmt.Errorf("version: cannot upgrade cluster from %s to %s, upgrade one major version at a time", from, to)
+	}
+	return false, nil
+}
+
+// splitClusterVersion splits a version such as apache-cassandra-3.0.10 into
+// its distribution and version number. The version is nil if it can't be parsed.
+func splitClusterVersion(v string) (string, *version.Version) {
+	i := strings.LastIndex(v, "-")
+	if i < 0 {
+		return v, nil
+	}
+	parsed, err := version.NewVersion(v[i+1:])
+	if err != nil {
+		return v[:i], nil
+	}
+	return v[:i], parsed
+}
+
+// defaultTags returns the provider level tags applied to every cluster
+func defaultTags(m interface{}) map[string]string {
+	if client, ok := m.(*InstaclustrClient); ok {
+		return client.config.DefaultTags
+	}
+	return nil
+}
 
-	datacenter := cluster.Datacenters[0]
-	node := datacenter.Nodes[0]
-	dcResource := d.Get("datacenter")
-	dc := dcResource.([]interface{})[0].(map[string]interface{})
-	dc["provider_name"] = datacenter.Provider
-	dc["region"] = datacenter.Name
-	dc["size"] = node.Size
-	dc["auth"] = datacenter.PasswordAuthentication && datacenter.UserAuthorization
-	dc["use_private_rpc_broadcast_address"] = datacenter.UsePrivateBroadcastRPCAddress
-	dc["default_network"] = fmt.Sprintf("%s/%d", cluster.ClusterNetwork.Network, cluster.ClusterNetwork.PrefixLength)
+func expandTags(tags map[string]interface{}) map[string]string {
+	result := map[string]string{}
+	for k, v := range tags {
+		result[k] = v.(string)
+	}
+	return result
+}
+
+// mergeTags merges resource tags over the provider default tags
+func mergeTags(defaults, tags map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range defaults {
+		result[k] = v
+	}
+	for k, v := range tags {
+		result[k] = v
+	}
+	return result
+}
+
+// resourceTags removes the default tags from the cluster's tags, unless the
+// resource sets the tag itself
+func resourceTags(tags, defaults map[string]string, configured map[string]interface{}) map[string]string {
+	result := map[string]string{}
+	for k, v := range tags {
+		if _, ok := configured[k]; !ok && defaults[k] == v {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// rackNodeCounts returns the node count for each rack in a rack set
+func rackNodeCounts(racks *schema.Set) map[string]int {
+	counts := map[string]int{}
+	for _, rack := range racks.List() {
+		r := rack.(map[string]interface{})
+		counts[r["name"].(string)] = r["node_count"].(int)
+	}
+	return counts
+}
+
+func sameRacks(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// datacenterClientEncryption returns whether client encryption is enabled for
+// the datacenter at index. Without client_encryption set, it follows
+// disk_encryption_key as earlier versions did, which is deprecated.
+func datacenterClientEncryption(d *schema.ResourceData, index int) bool {
+	if v, ok := d.GetOkExists(fmt.Sprintf("datacenter.%d.client_encryption", index)); ok {
+		return v.(bool)
+	}
+	return d.Get(fmt.Sprintf("datacenter.%d.disk_encryption_key", index)).(string) != ""
+}
+
+// expandDatacenterRegion builds the region section of a create or add datacenter request
+func expandDatacenterRegion(datacenter map[string]interface{}, clientEncryption bool) CreateClusterRequestRegion {
+	region := CreateClusterRequestRegion{
+		Datacenter:                    datacenter["region"].(string),
+		DatacenterCustomName:          datacenter["name"].(string),
+		UsePrivateBroadcastRPCAddress: datacenter["use_private_rpc_broadcast_address"].(bool),
+		DefaultNetwork:                datacenter["default_network"].(string),
+		AuthnAuthz:                    datacenter["auth"].(bool),
+		ClientEncryption:              clientEncryption,
+		RackAllocations:               []CreateClusterRequestRegionRackAllocation{},
+		FirewallRules:                 []string{},
+	}
+	if rules, ok := datacenter["initial_firewall_rules"]; ok {
+		region.FirewallRules = expandStringList(rules.([]interface{}))
+	}
+	if key, ok := datacenter["disk_encryption_key"]; ok && key != "" {
+		region.DiskEncryptionKey = key.(string)
+	}
+	for _, rack := range datacenter["rack"].(*schema.Set).List() {
+		alloc := rack.(map[string]interface{})
+		region.RackAllocations = append(region.RackAllocations, CreateClusterRequestRegionRackAllocation{
+			Name:      alloc["name"].(string),
+			NodeCount: alloc["node_count"].(int),
+		})
+	}
+	return region
+}
+
+// flattenDatacenters converts the cluster's datacenters to state, keeping the
+// order of the datacenters already known and appending any others
+func flattenDatacenters(cluster *ClusterStatus, existing []interface{}) []interface{} {
+	matched := make([]bool, len(cluster.Datacenters))
+	result := []interface{}{}
+	for _, e := range existing {
+		dc := e.(map[string]interface{})
+		for i, datacenter := range cluster.Datacenters {
+			if !matched[i] && datacenterMatches(datacenter, dc) {
+				matched[i] = true
+				result = append(result, flattenDatacenter(cluster, i, dc))
+				break
+			}
+		}
+	}
+	for i := range cluster.Datacenters {
+		if !matched[i] {
+			result = append(result, flattenDatacenter(cluster, i, map[string]interface{}{}))
+		}
+	}
+	return result
+}
+
+// datacenterMatches reports whether the API datacenter corresponds to a
+// datacenter in state, either by ID or by region and name for new datacenters
+func datacenterMatches(datacenter Datacenter, dc map[string]interface{}) bool {
+	if id, ok := dc["datacenter_id"].(string); ok && id != "" {
+		return id == datacenter.ID
+	}
+	if name, ok := dc["name"].(string); ok && name != "" && datacenter.CustomName != "" && name != datacenter.CustomName {
+		return false
+	}
+	return dc["region"] == datacenter.Name
+}
+
+// flattenDatacenter converts an API datacenter to state. initial_firewall_rules
+// only apply when the datacenter is created, so the known value is kept as is.
+func flattenDatacenter(cluster *ClusterStatus, index int, dc map[string]interface{}) map[string]interface{} {
+	datacenter := cluster.Datacenters[index]
+	result := map[string]interface{}{}
+	for k, v := range dc {
+		result[k] = v
+	}
+	result["datacenter_id"] = datacenter.ID
+	result["provider_name"] = datacenter.Provider
+	result["region"] = datacenter.Name
+	if datacenter.CustomName != "" {
+		result["name"] = datacenter.CustomName
+	}
+	if datacenter.ProviderAccountName != "" {
+		result["account"] = datacenter.ProviderAccountName
+	}
+	if datacenter.EncryptionKeyID != "" {
+		result["disk_encryption_key"] = diskEncryptionKey(datacenter.EncryptionKeyID, dc["disk_encryption_key"])
+	}
+	if _, ok := dc["concurrent_resizes"]; !ok {
+		// Imported datacenters have no resize setting, use the schema default
+		result["concurrent_resizes"] = 1
+	}
+	// auth enables both password authentication and user authorization
+	if datacenter.PasswordAuthentication != datacenter.UserAuthorization {
+		log.Printf("[WARN] Datacenter (%s) has password authentication %t and user authorization %t, auth can only manage both together",
+			datacenter.ID, datacenter.PasswordAuthentication, datacenter.UserAuthorization)
+	}
+	result["auth"] = datacenter.PasswordAuthentication && datacenter.UserAuthorization
+	result["client_encryption"] = datacenter.ClientEncryption
+	result["use_private_rpc_broadcast_address"] = datacenter.UsePrivateBroadcastRPCAddress
+	if index == 0 && cluster.ClusterNetwork.Network != "" {
+		result["default_network"] = fmt.Sprintf("%s/%d", cluster.ClusterNetwork.Network, cluster.ClusterNetwork.PrefixLength)
+	} else if index > 0 && datacenter.CdcNetwork != "" {
+		result["default_network"] = datacenter.CdcNetwork
+	}
+	if size := datacenterSize(datacenter, dc["size"]); size != "" {
+		result["size"] = size
+	}
 
 	racks := map[string]map[string]interface{}{}
+	publicIps := []string{}
+	privateIps := []string{}
 	for _, n := range datacenter.Nodes {
 		rack := racks[n.Rack]
 		if rack == nil {
@@ -215,37 +826,213 @@ func resourceInstaclustrClusterRead(d *schema.ResourceData, m interface{}) error
 			racks[n.Rack] = rack
 		}
 		rack["node_count"] = rack["node_count"].(int) + 1
+		if n.PrivateAddress != "" {
+			privateIps = append(privateIps, n.PrivateAddress)
+		}
+		if n.PublicAddress != "" {
+			publicIps = append(publicIps, n.PublicAddress)
+		}
 	}
-	rackSet := []interface{}{}
-	for _, rack := range racks {
-		rackSet = append(rackSet, rack)
+	// Nodes aren't listed until provisioning starts, so keep the configured
+	// racks for a cluster that was created without waiting
+	if len(datacenter.Nodes) > 0 {
+		// Racks are set as a *schema.Set, a list can't be written to a set
+		// nested in a list
+		rackSet := schema.NewSet(schema.HashResource(rackResource()), nil)
+		for _, rack := range racks {
+			rackSet.Add(rack)
+		}
+		result["rack"] = rackSet
 	}
-	dc["rack"] = rackSet
-	d.Set("datacenter", dcResource)
+	result["public_ips"] = publicIps
+	result["private_ips"] = privateIps
+	return result
+}
 
-	// Set computed values last after reaquiring the datacenter map
-	dcResource = d.Get("datacenter")
-	dc = dcResource.([]interface{})[0].(map[string]interface{})
-	dc["datacenter_id"] = datacenter.ID
-	dc["client_encryption"] = datacenter.ClientEncryption
-	d.Set("datacenter", dcResource)
+// diskEncryptionKey keeps a KMS key ARN from state when it refers to the key ID
+// returned by the API
+func diskEncryptionKey(keyID string, current interface{}) string {
+	if current, ok := current.(string); ok && strings.HasSuffix(current, ":key/"+keyID) {
+		return current
+	}
+	return keyID
+}
 
-	publicIps, privateIps := ipsForCluster(cluster)
-	d.Set("public_ips", publicIps)
-	d.Set("private_ips", privateIps)
+// equivalentKmsKeys reports whether two KMS keys are the same, either as the
+// same value or as a key ID and its ARN
+func equivalentKmsKeys(a, b string) bool {
+	return diskEncryptionKey(a, b) == b || diskEncryptionKey(b, a) == a
+}
+
+// addDatacenter adds a new datacenter to an existing cluster and waits for the cluster to be running
+func addDatacenter(client *ClusterClient, clusterID string, datacenter map[string]interface{}, clientEncryption bool, timeout time.Duration) error {
+	request := AddDatacenterRequest{
+		Provider:                   datacenter["provider_name"].(string),
+		Size:                       datacenter["size"].(string),
+		CreateClusterRequestRegion: expandDatacenterRegion(datacenter, clientEncryption),
+	}
+	if account, ok := datacenter["account"]; ok {
+		request.Account = account.(string)
+	}
+	response, err := client.AddDatacenter(clusterID, request)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Added Datacenter (%s) in %s to Cluster (%s)", response.ID, request.Datacenter, clusterID)
+	return waitForClusterRunning(client, clusterID, timeout)
+}
+
+// addNodes adds nodes to the racks of an existing datacenter and waits for them to be running
+func addNodes(client *ClusterClient, clusterID, datacenterID string, oldRacks, newRacks map[string]int, timeout time.Duration) error {
+	request := AddNodesRequest{
+		RackAllocations: []CreateClusterRequestRegionRackAllocation{},
+	}
+	for name, count := range newRacks {
+		if count > oldRacks[name] {
+			request.RackAllocations = append(request.RackAllocations, CreateClusterRequestRegionRackAllocation{
+				Name:      name,
+				NodeCount: count - oldRacks[name],
+			})
+		}
+	}
+	if len(request.RackAllocations) == 0 {
+		return nil
+	}
+	err := client.AddNodes(clusterID, datacenterID, request)
+	if err != nil {
+		return err
+	}
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"PROVISIONING"},
+		Target:     []string{"RUNNING"},
+		Refresh:    datacenterNodesStateRefreshFunc(client, clusterID, datacenterID, newRacks),
+		Timeout:    timeout,
+		Delay:      waitPollInterval,
+		MinTimeout: waitPollInterval,
+	}
+	_, waitErr := stateConf.WaitForState()
+	if waitErr != nil {
+		return fmt.Errorf(
+			"Error waiting for new nodes in Datacenter (%s) to be Running: %s", datacenterID, waitErr)
+	}
 	return nil
 }
 
-func resourceInstaclustrClusterDelete(d *schema.ResourceData, m interface{}) error {
-	client := m.(*InstaclustrClient).ClusterClient()
-	err := client.Delete(d.Id())
+// resizeDatacenter resizes the nodes of a datacenter and waits for every node to report the new size
+func resizeDatacenter(client *ClusterClient, clusterID, datacenterID, size string, concurrentResizes int, timeout time.Duration) error {
+	response, err := client.ResizeDatacenter(clusterID, datacenterID, ResizeDatacenterRequest{
+		NewNodeSize:       size,
+		ConcurrentResizes: concurrentResizes,
+	})
 	if err != nil {
 		return err
 	}
-	d.SetId("")
+	log.Printf("[INFO] Resizing Datacenter (%s) to %s, operation %s", datacenterID, size, response.OperationID)
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"RESIZING"},
+		Target:     []string{"RESIZED"},
+		Refresh:    datacenterResizeStateRefreshFunc(client, clusterID, datacenterID, size),
+		Timeout:    timeout,
+		Delay:      maintenancePollInterval,
+		MinTimeout: maintenancePollInterval,
+	}
+	_, waitErr := stateConf.WaitForState()
+	if waitErr != nil {
+		return fmt.Errorf(
+			"Error waiting for Datacenter (%s) to be resized to %s: %s", datacenterID, size, waitErr)
+	}
+	return nil
+}
+
+// datacenterSize returns the node size of a datacenter. While nodes have mixed
+// sizes, such as after an interrupted resize, it returns a size other than the
+// one in state so the next plan resumes the resize.
+func datacenterSize(datacenter Datacenter, current interface{}) string {
+	size := ""
+	for _, node := range datacenter.Nodes {
+		if size == "" {
+			size = node.Size
+		} else if node.Size != size {
+			log.Printf("[WARN] Datacenter (%s) has nodes of mixed sizes, a resize may have been interrupted", datacenter.ID)
+			for _, n := range datacenter.Nodes {
+				if n.Size != current {
+					return n.Size
+				}
+			}
+		}
+	}
+	return size
+}
+
+// upgradeCluster upgrades the cluster software in place and waits for every node to be running the new version
+func upgradeCluster(client *ClusterClient, clusterID, cassandraVersion string, timeout time.Duration) error {
+	err := client.Upgrade(clusterID, UpgradeClusterRequest{Version: cassandraVersion})
+	if err != nil {
+		return err
+	}
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"UPGRADING"},
+		Target:     []string{"UPGRADED"},
+		Refresh:    clusterUpgradeStateRefreshFunc(client, clusterID, cassandraVersion),
+		Timeout:    timeout,
+		Delay:      maintenancePollInterval,
+		MinTimeout: maintenancePollInterval,
+	}
+	_, waitErr := stateConf.WaitForState()
+	if waitErr != nil {
+		return fmt.Errorf(
+			"Error waiting for Cluster (%s) to be upgraded to %s: %s", clusterID, cassandraVersion, waitErr)
+	}
+	return nil
+}
+
+func waitForClusterRunning(client *ClusterClient, clusterID string, timeout time.Duration) error {
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"GENESIS", "PROVISIONING", "PROVISIONED"},
+		Target:     []string{"RUNNING"},
+		Refresh:    clusterStateRefreshFunc(client, clusterID),
+		Timeout:    timeout,
+		Delay:      waitPollInterval,
+		MinTimeout: waitPollInterval,
+	}
+	_, waitErr := stateConf.WaitForState()
+	if waitErr != nil {
+		return fmt.Errorf(
+			"Error waiting for Cluster (%s) to be Running: %s", clusterID, waitErr)
+	}
+	return nil
+}
+
+// waitForNodesRunning waits for the expected number of nodes across all of the
+// cluster's datacenters to be running
+func waitForNodesRunning(client *ClusterClient, clusterID string, expected int, timeout time.Duration) error {
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"PROVISIONING"},
+		Target:     []string{"RUNNING"},
+		Refresh:    clusterNodesStateRefreshFunc(client, clusterID, expected),
+		Timeout:    timeout,
+		Delay:      waitPollInterval,
+		MinTimeout: waitPollInterval,
+	}
+	_, waitErr := stateConf.WaitForState()
+	if waitErr != nil {
+		return fmt.Errorf(
+			"Error waiting for all nodes in Cluster (%s) to be Running: %s", clusterID, waitErr)
+	}
 	return nil
 }
 
+// datacenterNodeCount returns the total number of nodes configured across datacenters
+func datacenterNodeCount(datacenters []interface{}) int {
+	count := 0
+	for _, dc := range datacenters {
+		for _, n := range rackNodeCounts(dc.(map[string]interface{})["rack"].(*schema.Set)) {
+			count += n
+		}
+	}
+	return count
+}
+
 func datacenterHash(v interface{}) int {
 	var buf bytes.Buffer
 	datacenter := v.(map[string]interface{})
@@ -270,12 +1057,168 @@ func datacenterHash(v interface{}) int {
 	return hashcode.String(buf.String())
 }
 
+// clusterFailure returns an error if the cluster or any of its nodes are in a
+// state they won't recover from while waiting
+func clusterFailure(cluster *ClusterStatus) error {
+	failedNodes := []string{}
+	for _, datacenter := range cluster.Datacenters {
+		for _, node := range datacenter.Nodes {
+			if stringInSlice(node.NodeStatus, nodeFailureStates) {
+				failedNodes = append(failedNodes, fmt.Sprintf("%s (%s)", node.ID, node.NodeStatus))
+			}
+		}
+	}
+	if stringInSlice(cluster.ClusterStatus, clusterFailureStates) || len(failedNodes) > 0 {
+		message := fmt.Sprintf("Cluster (%s) is %s", cluster.ID, cluster.ClusterStatus)
+		if len(failedNodes) > 0 {
+			message = fmt.Sprintf("%s, failed nodes: %s", message, strings.Join(failedNodes, ", "))
+		}
+		return errors.New(message)
+	}
+	return nil
+}
+
 func clusterStateRefreshFunc(client *ClusterClient, clusterID string) resource.StateRefreshFunc {
 	return func() (interface{}, string, error) {
 		cluster, err := client.Get(clusterID)
 		if err != nil {
 			return nil, "", err
 		}
+		if err := clusterFailure(cluster); err != nil {
+			return nil, "", err
+		}
 		return cluster, cluster.ClusterStatus, nil
 	}
 }
+
+// clusterDeleteStateRefreshFunc reports a missing cluster as DELETED
+func clusterDeleteStateRefreshFunc(client *ClusterClient, clusterID string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		cluster, err := client.Get(clusterID)
+		if err != nil {
+			if IsNotFound(err) {
+				return &ClusterStatus{ID: clusterID, ClusterStatus: "DELETED"}, "DELETED", nil
+			}
+			return nil, "", err
+		}
+		return cluster, cluster.ClusterStatus, nil
+	}
+}
+
+// clusterNodesStateRefreshFunc reports RUNNING once the cluster has at least
+// the expected number of running nodes
+func clusterNodesStateRefreshFunc(client *ClusterClient, clusterID string, expected int) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		cluster, err := client.Get(clusterID)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := clusterFailure(cluster); err != nil {
+			return nil, "", err
+		}
+		running := 0
+		for _, datacenter := range cluster.Datacenters {
+			for _, node := range datacenter.Nodes {
+				if node.NodeStatus == "RUNNING" {
+					running++
+				}
+			}
+		}
+		if running < expected {
+			log.Printf("[INFO] Waiting for Cluster (%s): %d/%d nodes RUNNING", clusterID, running, expected)
+			return cluster, "PROVISIONING", nil
+		}
+		return cluster, "RUNNING", nil
+	}
+}
+
+// datacenterNodesStateRefreshFunc reports RUNNING once every rack in the
+// datacenter has at least the expected number of running nodes
+func datacenterNodesStateRefreshFunc(client *ClusterClient, clusterID, datacenterID string, racks map[string]int) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		cluster, err := client.Get(clusterID)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := clusterFailure(cluster); err != nil {
+			return nil, "", err
+		}
+		running := map[string]int{}
+		for _, datacenter := range cluster.Datacenters {
+			if datacenter.ID != datacenterID {
+				continue
+			}
+			for _, node := range datacenter.Nodes {
+				if node.NodeStatus == "RUNNING" {
+					running[node.Rack]++
+				}
+			}
+		}
+		for name, count := range racks {
+			if running[name] < count {
+				log.Printf("[INFO] Waiting for rack %s in Datacenter (%s): %d/%d nodes RUNNING", name, datacenterID, running[name], count)
+				return cluster, "PROVISIONING", nil
+			}
+		}
+		return cluster, "RUNNING", nil
+	}
+}
+
+// datacenterResizeStateRefreshFunc reports RESIZED once every node in the
+// datacenter is running with the new size
+func datacenterResizeStateRefreshFunc(client *ClusterClient, clusterID, datacenterID, size string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		cluster, err := client.Get(clusterID)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := clusterFailure(cluster); err != nil {
+			return nil, "", err
+		}
+		resized, total := 0, 0
+		for _, datacenter := range cluster.Datacenters {
+			if datacenter.ID != datacenterID {
+				continue
+			}
+			for _, node := range datacenter.Nodes {
+				total++
+				if node.Size == size && node.NodeStatus == "RUNNING" {
+					resized++
+				}
+			}
+		}
+		log.Printf("[INFO] Resizing Datacenter (%s) to %s: %d/%d nodes resized", datacenterID, size, resized, total)
+		if total == 0 || resized < total {
+			return cluster, "RESIZING", nil
+		}
+		return cluster, "RESIZED", nil
+	}
+}
+
+// clusterUpgradeStateRefreshFunc reports UPGRADED once the cluster reports the
+// new version and every node is running again
+func clusterUpgradeStateRefreshFunc(client *ClusterClient, clusterID, cassandraVersion string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		cluster, err := client.Get(clusterID)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := clusterFailure(cluster); err != nil {
+			return nil, "", err
+		}
+		running, total := 0, 0
+		for _, datacenter := range cluster.Datacenters {
+			for _, node := range datacenter.Nodes {
+				total++
+				if node.NodeStatus == "RUNNING" {
+					running++
+				}
+			}
+		}
+		log.Printf("[INFO] Upgrading Cluster (%s) to %s: version %s, %d/%d nodes RUNNING", clusterID, cassandraVersion, cluster.CassandraVersion, running, total)
+		if cluster.CassandraVersion != cassandraVersion || running < total {
+			return cluster, "UPGRADING", nil
+		}
+		return cluster, "UPGRADED", nil
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceClusterFirewall() *schema.Resource {
+	return &schema.Resource{
+		Create:        resourceInstaclustrClusterFirewallCreate,
+		Read:          resourceInstaclustrClusterFirewallRead,
+		Update:        resourceInstaclustrClusterFirewallUpdate,
+		Delete:        resourceInstaclustrClusterFirewallDelete,
+		CustomizeDiff: resourceInstaclustrClusterFirewallCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"cluster_id": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateUUID,
+			},
+			"rule": &schema.Schema{
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"network": &schema.Schema{
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validateCIDR,
+						},
+						"security_group_id": &schema.Schema{
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validateSecurityGroupID,
+						},
+						"rule_types": &schema.Schema{
+							Type:     schema.TypeSet,
+							Required: true,
+							MinItems: 1,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: stringInList(firewallRuleTypes),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceInstaclustrClusterFirewallCreate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*InstaclustrClient).FirewallClient()
+	clusterID := d.Get("cluster_id").(string)
+	firewallRules, err := client.List(clusterID)
+	if err != nil {
+		return err
+	}
+	err = convergeFirewall(client, clusterID, firewallRuleMap(firewallRules), expandFirewallRuleSet(d.Get("rule").(*schema.Set)))
+	if err != nil {
+		return err
+	}
+	d.SetId(clusterID)
+	return resourceInstaclustrClusterFirewallRead(d, m)
+}
+
+func resourceInstaclustrClusterFirewallRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*InstaclustrClient).FirewallClient()
+	firewallRules, err := client.List(d.Id())
+	if err != nil {
+		if IsNotFound(err) {
+			log.Printf("[WARN] Cluster (%s) for Cluster Firewall not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+	rules := []interface{}{}
+	for _, f := range firewallRules {
+		rules = append(rules, map[string]interface{}{
+			"network":           f.Network,
+			"security_group_id": f.SecurityGroupID,
+			"rule_types":        firewallRuleTypeNames(f.Rules),
+		})
+	}
+	d.Set("cluster_id", d.Id())
+	d.Set("rule", rules)
+	return nil
+}
+
+func resourceInstaclustrClusterFirewallUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*InstaclustrClient).FirewallClient()
+	if d.HasChange("rule") {
+		o, n := d.GetChange("rule")
+		err := convergeFirewall(client, d.Id(), expandFirewallRuleSet(o.(*schema.Set)), expandFirewallRuleSet(n.(*schema.Set)))
+		if err != nil {
+			return err
+		}
+	}
+	return resourceInstaclustrClusterFirewallRead(d, m)
+}
+
+func resourceInstaclustrClusterFirewallDelete(d *schema.ResourceData, m interface{}) error {
+	client := m.(*InstaclustrClient).FirewallClient()
+	err := convergeFirewall(client, d.Id(), expandFirewallRuleSet(d.Get("rule").(*schema.Set)), map[string][]string{})
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	d.SetId("")
+	return nil
+}
+
+// resourceInstaclustrClusterFirewallCustomizeDiff requires exactly one of
+// network or security_group_id on each rule
+func resourceInstaclustrClusterFirewallCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("rule") {
+		return nil
+	}
+	sources := map[string]bool{}
+	for _, r := range d.Get("rule").(*schema.Set).List() {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		network, _ := rule["network"].(string)
+		securityGroupID, _ := rule["security_group_id"].(string)
+		if network == config.UnknownVariableValue || securityGroupID == config.UnknownVariableValue {
+			continue
+		}
+		if network == "" && securityGroupID == "" {
+			return errors.New("rule: one of network or security_group_id must be set")
+		}
+		if network != "" && securityGroupID != "" {
+			return fmt.Errorf("rule: only one of network or security_group_id can be set, got %s and %s", network, securityGroupID)
+		}
+		source := network + securityGroupID
+		if sources[source] {
+			return fmt.Errorf("rule: %s is the source of more than one rule, combine their rule_types", source)
+		}
+		sources[source] = true
+	}
+	return nil
+}
+
+// convergeFirewall adds and removes rule types so the cluster's firewall
+// changes from the current rules to the desired rules
+func convergeFirewall(client *FirewallClient, clusterID string, current, desired map[string][]string) error {
+	for source, ruleTypes := range desired {
+		added := stringsDifference(ruleTypes, current[source])
+		if len(added) > 0 {
+			log.Printf("[DEBUG] Adding %v for %s to Cluster (%s) firewall", added, source, clusterID)
+			err := client.Create(clusterID, source, added)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for source, ruleTypes := range current {
+		removed := stringsDifference(ruleTypes, desired[source])
+		if len(removed) > 0 {
+			log.Printf("[DEBUG] Removing %v for %s from Cluster (%s) firewall", removed, source, clusterID)
+			err := client.Delete(clusterID, source, removed)
+			if err != nil && !IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// firewallRuleMap returns the rule types for each network or security group of the firewall rules
+func firewallRuleMap(firewallRules []*Firewall) map[string][]string {
+	rules := map[string][]string{}
+	for _, f := range firewallRules {
+		rules[f.Source()] = append(rules[f.Source()], firewallRuleTypeNames(f.Rules)...)
+	}
+	return rules
+}
+
+func expandFirewallRuleSet(set *schema.Set) map[string][]string {
+	rules := map[string][]string{}
+	for _, r := range set.List() {
+		rule := r.(map[string]interface{})
+		source := rule["network"].(string)
+		if securityGroupID := rule["security_group_id"].(string); securityGroupID != "" {
+			source = securityGroupID
+		}
+		rules[source] = append(rules[source], expandStringSet(rule["rule_types"].(*schema.Set))...)
+	}
+	return rules
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccInstaclustrClusterFirewall_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstaclustrClusterFirewallDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccInstaclustrClusterFirewallConfig,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstaclustrClusterFirewallRules("instaclustr_cluster_firewall.foo", 2),
+					resource.TestCheckResourceAttr("instaclustr_cluster_firewall.foo", "rule.#", "2"),
+				),
+			},
+		},
+	})
+}
+
+func TestConvergeFirewall(t *testing.T) {
+	requests := []string{}
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		firewall := Firewall{}
+		json.Unmarshal(body, &firewall)
+		requests = append(requests, fmt.Sprintf("%s %s %v", r.Method, firewall.Source(), firewallRuleTypeNames(firewall.Rules)))
+		w.WriteHeader(http.StatusAccepted)
+	})
+	defer done()
+
+	current := map[string][]string{
+		"10.1.0.0/16": []string{"CASSANDRA", "SPARK"},
+		"10.2.0.0/16": []string{"CASSANDRA"},
+	}
+	desired := map[string][]string{
+		"10.1.0.0/16": []string{"CASSANDRA", "KAFKA"},
+		"10.3.0.0/16": []string{"CASSANDRA"},
+		"sg-12345678": []string{"CASSANDRA"},
+	}
+	err := convergeFirewall(client.FirewallClient(), "cluster", current, desired)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	sort.Strings(requests)
+	expected := []string{
+		"DELETE 10.1.0.0/16 [SPARK]",
+		"DELETE 10.2.0.0/16 [CASSANDRA]",
+		"POST 10.1.0.0/16 [KAFKA]",
+		"POST 10.3.0.0/16 [CASSANDRA]",
+		"POST sg-12345678 [CASSANDRA]",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected requests:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(requests, "\n"))
+	}
+}
+
+func TestResourceClusterFirewallDiff_ruleSource(t *testing.T) {
+	cassandra := []interface{}{"CASSANDRA"}
+	spark := []interface{}{"SPARK"}
+	cases := map[string]struct {
+		Rules []interface{}
+		Error string
+	}{
+		"network": {
+			Rules: []interface{}{map[string]interface{}{"network": "10.1.0.0/16", "rule_types": cassandra}},
+		},
+		"security group": {
+			Rules: []interface{}{map[string]interface{}{"security_group_id": "sg-1a2b3c4d", "rule_types": cassandra}},
+		},
+		"neither": {
+			Rules: []interface{}{map[string]interface{}{"rule_types": cassandra}},
+			Error: "one of network or security_group_id must be set",
+		},
+		"both": {
+			Rules: []interface{}{map[string]interface{}{"network": "10.1.0.0/16", "security_group_id": "sg-1a2b3c4d", "rule_types": cassandra}},
+			Error: "only one of network or security_group_id can be set",
+		},
+		"different sources": {
+			Rules: []interface{}{
+				map[string]interface{}{"network": "10.1.0.0/16", "rule_types": cassandra},
+				map[string]interface{}{"network": "10.2.0.0/16", "rule_types": spark},
+			},
+		},
+		"duplicate network": {
+			Rules: []interface{}{
+				map[string]interface{}{"network": "10.1.0.0/16", "rule_types": cassandra},
+				map[string]interface{}{"network": "10.1.0.0/16", "rule_types": spark},
+			},
+			Error: "10.1.0.0/16 is the source of more than one rule",
+		},
+		"duplicate security group": {
+			Rules: []interface{}{
+				map[string]interface{}{"security_group_id": "sg-1a2b3c4d", "rule_types": cassandra},
+				map[string]interface{}{"security_group_id": "sg-1a2b3c4d", "rule_types": spark},
+			},
+			Error: "sg-1a2b3c4d is the source of more than one rule",
+		},
+	}
+	for name, tc := range cases {
+		c, err := config.NewRawConfig(map[string]interface{}{
+			"cluster_id": "0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11",
+			"rule":       tc.Rules,
+		})
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		_, err = resourceClusterFirewall().Diff(&terraform.InstanceState{}, terraform.NewResourceConfig(c), nil)
+		if tc.Error == "" && err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if tc.Error != "" && (err == nil || !strings.Contains(err.Error(), tc.Error)) {
+			t.Fatalf("%s: expected error containing %q, got %v", name, tc.Error, err)
+		}
+	}
+}
+
+func testAccCheckInstaclustrClusterFirewallRules(n string, count int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("Cluster Firewall does not exists in state")
+		}
+		client := testAccProvider.Meta().(*InstaclustrClient).FirewallClient()
+		firewalls, err := client.List(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if len(firewalls) != count {
+			return fmt.Errorf("Expected %d Firewall Rules, found %d", count, len(firewalls))
+		}
+		return nil
+	}
+}
+
+func testAccCheckInstaclustrClusterFirewallDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*InstaclustrClient).FirewallClient()
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "instaclustr_cluster_firewall" {
+			continue
+		}
+		firewalls, err := client.List(rs.Primary.ID)
+		if err == nil && len(firewalls) > 0 {
+			return fmt.Errorf("Cluster Firewall Rules still exist")
+		}
+	}
+	return nil
+}
+
+const testAccInstaclustrClusterFirewallConfig = `
+resource "instaclustr_cluster" "foo" {
+  name = "terraform-test-acc"
+  version = "apache-cassandra-3.0.10"
+  datacenter {
+    provider_name = "AWS_VPC"
+    account = "PeopleNet"
+    region = "US_EAST_1"
+    size = "t2.small"
+    default_network = "10.0.0.0/16"
+    rack {
+      name = "us-east-1a"
+      node_count = 1
+    }
+    rack {
+      name = "us-east-1b"
+      node_count = 1
+    }
+  }
+}
+
+resource "instaclustr_cluster_firewall" "foo" {
+  cluster_id = "${instaclustr_cluster.foo.id}"
+  rule {
+    network = "10.1.0.0/16"
+    rule_types = ["CASSANDRA"]
+  }
+  rule {
+    network = "10.2.0.0/16"
+    rule_types = ["CASSANDRA", "CASSANDRA_THRIFT"]
+  }
+}
+`
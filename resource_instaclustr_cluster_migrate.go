@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// clusterV0DatacenterKey matches the datacenter attributes of v0 state, which
+// are either indexed by 0 or, from before datacenter became a list, by hash
+var clusterV0DatacenterKey = regexp.MustCompile(`^datacenter\.(\d+)\.(.+)$`)
+
+// clusterV0RackKey matches the rack attributes of a migrated datacenter
+var clusterV0RackKey = regexp.MustCompile(`^datacenter\.0\.rack\.(\d+)\.(name|node_count)$`)
+
+func resourceInstaclustrClusterMigrateState(v int, is *terraform.InstanceState, meta interface{}) (*terraform.InstanceState, error) {
+	switch v {
+	case 0:
+		log.Println("[INFO] Found Instaclustr Cluster State v0; migrating to v1")
+		return migrateClusterStateV0toV1(is)
+	default:
+		return is, fmt.Errorf("Unexpected schema version: %d", v)
+	}
+}
+
+// migrateClusterStateV0toV1 rewrites the single datacenter state written before
+// clusters supported multiple datacenters and in place changes. The datacenter
+// is moved to index 0, its racks are rehashed and the arguments added since
+// are set to their defaults.
+func migrateClusterStateV0toV1(is *terraform.InstanceState) (*terraform.InstanceState, error) {
+	if is.Empty() || is.Attributes == nil {
+		log.Println("[DEBUG] Empty Cluster State; nothing to migrate.")
+		return is, nil
+	}
+	log.Printf("[DEBUG] Cluster Attributes before migration: %#v", is.Attributes)
+
+	datacenters := map[string]bool{}
+	for k := range is.Attributes {
+		if m := clusterV0DatacenterKey.FindStringSubmatch(k); m != nil {
+			datacenters[m[1]] = true
+		}
+	}
+	if len(datacenters) > 1 {
+		return is, fmt.Errorf("Cluster (%s) state has %d datacenters, v0 state supports only one", is.ID, len(datacenters))
+	}
+	for index := range datacenters {
+		if index == "0" {
+			continue
+		}
+		for k, v := range is.Attributes {
+			if m := clusterV0DatacenterKey.FindStringSubmatch(k); m != nil {
+				delete(is.Attributes, k)
+				is.Attributes["datacenter.0."+m[2]] = v
+			}
+		}
+	}
+
+	if len(datacenters) > 0 {
+		is.Attributes["datacenter.#"] = "1"
+		if _, ok := is.Attributes["datacenter.0.concurrent_resizes"]; !ok {
+			is.Attributes["datacenter.0.concurrent_resizes"] = "1"
+		}
+		if err := migrateClusterRacksV0toV1(is); err != nil {
+			return is, err
+		}
+	}
+	if _, ok := is.Attributes["wait_for"]; !ok {
+		is.Attributes["wait_for"] = "cluster_running"
+	}
+
+	log.Printf("[DEBUG] Cluster Attributes after migration: %#v", is.Attributes)
+	return is, nil
+}
+
+// migrateClusterRacksV0toV1 rehashes the racks of the migrated datacenter with
+// the current rack schema
+func migrateClusterRacksV0toV1(is *terraform.InstanceState) error {
+	racks := map[string]map[string]interface{}{}
+	for k, v := range is.Attributes {
+		m := clusterV0RackKey.FindStringSubmatch(k)
+		if m == nil {
+			continue
+		}
+		delete(is.Attributes, k)
+		rack := racks[m[1]]
+		if rack == nil {
+			rack = map[string]interface{}{}
+			racks[m[1]] = rack
+		}
+		if m[2] == "node_count" {
+			count, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("Cluster (%s) state has invalid rack node_count %q: %s", is.ID, v, err)
+			}
+			rack[m[2]] = count
+		} else {
+			rack[m[2]] = v
+		}
+	}
+	hash := schema.HashResource(rackResource())
+	for _, rack := range racks {
+		prefix := fmt.Sprintf("datacenter.0.rack.%d.", hash(rack))
+		for k, v := range rack {
+			is.Attributes[prefix+k] = fmt.Sprintf("%v", v)
+		}
+	}
+	is.Attributes["datacenter.0.rack.#"] = strconv.Itoa(len(racks))
+	return nil
+}
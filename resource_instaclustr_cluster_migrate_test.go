@@ -0,0 +1,172 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// testClusterStateV0 is the state of a cluster written by the single
+// datacenter version of the provider
+var testClusterStateV0 = map[string]string{
+	"id":                               "cluster-id",
+	"name":                             "terraform-test",
+	"version":                          "apache-cassandra-3.0.10",
+	"private_ips.#":                    "2",
+	"private_ips.0":                    "10.0.0.1",
+	"private_ips.1":                    "10.0.0.2",
+	"public_ips.#":                     "0",
+	"datacenter.#":                     "1",
+	"datacenter.0.provider_name":       "AWS_VPC",
+	"datacenter.0.account":             "",
+	"datacenter.0.region":              "US_EAST_1",
+	"datacenter.0.size":                "t2.small",
+	"datacenter.0.datacenter_id":       "dc-1",
+	"datacenter.0.auth":                "false",
+	"datacenter.0.client_encryption":   "false",
+	"datacenter.0.disk_encryption_key": "",
+	"datacenter.0.use_private_rpc_broadcast_address": "true",
+	"datacenter.0.default_network":                   "10.0.0.0/16",
+	"datacenter.0.rack.#":                            "2",
+	"datacenter.0.rack.1016557208.name":              "a",
+	"datacenter.0.rack.1016557208.node_count":        "1",
+	"datacenter.0.rack.770311393.name":               "b",
+	"datacenter.0.rack.770311393.node_count":         "1",
+}
+
+func TestResourceInstaclustrClusterMigrateState(t *testing.T) {
+	cases := map[string]struct {
+		Attributes map[string]string
+		Expected   map[string]string
+	}{
+		"v0 list": {
+			Attributes: testClusterStateV0,
+			Expected: map[string]string{
+				"datacenter.#":                            "1",
+				"datacenter.0.region":                     "US_EAST_1",
+				"datacenter.0.concurrent_resizes":         "1",
+				"datacenter.0.rack.#":                     "2",
+				"datacenter.0.rack.1016557208.name":       "a",
+				"datacenter.0.rack.1016557208.node_count": "1",
+				"datacenter.0.rack.770311393.name":        "b",
+				"datacenter.0.rack.770311393.node_count":  "1",
+				"wait_for":                                "cluster_running",
+			},
+		},
+		"v0 hashed datacenter and racks": {
+			Attributes: map[string]string{
+				"name":                                        "terraform-test",
+				"datacenter.#":                                "1",
+				"datacenter.2887397591.region":                "US_EAST_1",
+				"datacenter.2887397591.rack.#":                "2",
+				"datacenter.2887397591.rack.12345.name":       "a",
+				"datacenter.2887397591.rack.12345.node_count": "3",
+				"datacenter.2887397591.rack.67890.name":       "b",
+				"datacenter.2887397591.rack.67890.node_count": "1",
+			},
+			Expected: map[string]string{
+				"datacenter.#":                           "1",
+				"datacenter.0.region":                    "US_EAST_1",
+				"datacenter.0.concurrent_resizes":        "1",
+				"datacenter.0.rack.#":                    "2",
+				"datacenter.0.rack.245435418.name":       "a",
+				"datacenter.0.rack.245435418.node_count": "3",
+				"datacenter.0.rack.770311393.name":       "b",
+				"datacenter.0.rack.770311393.node_count": "1",
+				"wait_for":                               "cluster_running",
+			},
+		},
+		"v0 without datacenter": {
+			Attributes: map[string]string{
+				"name": "terraform-test",
+			},
+			Expected: map[string]string{
+				"name":     "terraform-test",
+				"wait_for": "cluster_running",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		attributes := map[string]string{}
+		for k, v := range tc.Attributes {
+			attributes[k] = v
+		}
+		is := &terraform.InstanceState{
+			ID:         "cluster-id",
+			Attributes: attributes,
+		}
+		is, err := resourceCluster().MigrateState(0, is, nil)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		for k, v := range tc.Expected {
+			if is.Attributes[k] != v {
+				t.Fatalf("%s: expected %s to be %q, got %q in %#v", name, k, v, is.Attributes[k], is.Attributes)
+			}
+		}
+		for k := range is.Attributes {
+			if m := clusterV0DatacenterKey.FindStringSubmatch(k); m != nil && m[1] != "0" {
+				t.Fatalf("%s: expected datacenter attributes to be moved to index 0, got %s", name, k)
+			}
+		}
+	}
+}
+
+// TestResourceInstaclustrClusterMigrateState_planIsClean migrates v0 state and
+// refreshes it, as Terraform does before planning
+func TestResourceInstaclustrClusterMigrateState_planIsClean(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterName":"terraform-test","cassandraVersion":"apache-cassandra-3.0.10",
+			"clusterStatus":"RUNNING","clusterNetwork":{"network":"10.0.0.0","prefixLength":16},"dataCentres":[
+			{"id":"dc-1","name":"US_EAST_1","provider":"AWS_VPC","usePrivateBroadcastRPCAddress":true,"nodes":[
+			{"id":"node-1","size":"t2.small","rack":"a","privateAddress":"10.0.0.1","nodeStatus":"RUNNING"},
+			{"id":"node-2","size":"t2.small","rack":"b","privateAddress":"10.0.0.2","nodeStatus":"RUNNING"}]}]}`))
+	})
+	defer done()
+
+	is := &terraform.InstanceState{
+		ID:         "cluster-id",
+		Attributes: map[string]string{},
+	}
+	for k, v := range testClusterStateV0 {
+		is.Attributes[k] = v
+	}
+	r := resourceCluster()
+	is, err := r.MigrateState(0, is, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	is, err = r.Refresh(is, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c, err := config.NewRawConfig(testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	diff, err := r.Diff(is, terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff != nil && len(diff.Attributes) > 0 {
+		for k, attr := range diff.Attributes {
+			t.Errorf("unexpected diff for %s: %#v", k, attr)
+		}
+		t.Fatalf("expected no diff after migration")
+	}
+}
+
+func TestResourceInstaclustrClusterMigrateState_empty(t *testing.T) {
+	var is *terraform.InstanceState
+	is, err := resourceCluster().MigrateState(0, is, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if is != nil {
+		t.Fatalf("expected nil state to stay nil, got %#v", is)
+	}
+}
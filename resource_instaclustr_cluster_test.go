@@ -1,10 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -26,6 +32,723 @@ func TestAccInstaclustrCluster_basic(t *testing.T) {
 	})
 }
 
+func TestAccInstaclustrCluster_multiDatacenter(t *testing.T) {
+	var cluster ClusterStatus
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstaclustrClusterDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccInstaclustrClusterConfig,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstaclustrClusterExists("instaclustr_cluster.foo", &cluster),
+					resource.TestCheckResourceAttr("instaclustr_cluster.foo", "datacenter.#", "1"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccInstaclustrClusterMultiDatacenterConfig,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstaclustrClusterExists("instaclustr_cluster.foo", &cluster),
+					resource.TestCheckResourceAttr("instaclustr_cluster.foo", "datacenter.#", "2"),
+					resource.TestCheckResourceAttrSet("instaclustr_cluster.foo", "datacenter.1.datacenter_id"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceClusterDiff_addDatacenterInPlace(t *testing.T) {
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	new := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"), testClusterRawDatacenter("US_WEST_2", "10.1.0.0/16"))
+	diff := testClusterDiff(t, old, new)
+	if diff.RequiresNew() {
+		t.Fatalf("expected adding a datacenter to update in place, got %#v", diff)
+	}
+	if diff.Attributes["datacenter.#"] == nil || diff.Attributes["datacenter.#"].New != "2" {
+		t.Fatalf("expected datacenter count to change to 2, got %#v", diff.Attributes["datacenter.#"])
+	}
+}
+
+func TestResourceInstaclustrClusterUpdate_addDatacenter(t *testing.T) {
+	var request AddDatacenterRequest
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if r.URL.Path != "/cluster-id/cluster-data-centres" {
+				t.Errorf("unexpected request to %s", r.URL.Path)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Errorf("err: %s", err)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"dc-2"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterName":"terraform-test","cassandraVersion":"apache-cassandra-3.0.10",
+			"clusterStatus":"RUNNING","clusterNetwork":{"network":"10.0.0.0","prefixLength":16},"dataCentres":[
+			{"id":"dc-1","name":"US_EAST_1","provider":"AWS_VPC","nodes":[
+			{"id":"node-1","size":"t2.small","rack":"a","nodeStatus":"RUNNING"},
+			{"id":"node-2","size":"t2.small","rack":"b","nodeStatus":"RUNNING"}]},
+			{"id":"dc-2","name":"US_WEST_2","provider":"AWS_VPC","clientEncryption":true,
+			"cdcNetwork":"10.1.0.0/16","nodes":[
+			{"id":"node-3","size":"t2.small","rack":"a","nodeStatus":"RUNNING"},
+			{"id":"node-4","size":"t2.small","rack":"b","nodeStatus":"RUNNING"}]}]}`))
+	})
+	defer done()
+
+	added := testClusterRawDatacenter("US_WEST_2", "10.1.0.0/16")
+	added["client_encryption"] = true
+	added["initial_firewall_rules"] = []interface{}{"10.2.0.0/16"}
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	new := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"), added)
+	state, err := testClusterApply(t, old, new, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if request.Provider != "AWS_VPC" || request.Datacenter != "US_WEST_2" || request.DefaultNetwork != "10.1.0.0/16" {
+		t.Fatalf("expected datacenter in US_WEST_2 on 10.1.0.0/16, got %#v", request)
+	}
+	if !request.ClientEncryption {
+		t.Fatalf("expected client encryption to be requested")
+	}
+	if len(request.FirewallRules) != 1 || request.FirewallRules[0] != "10.2.0.0/16" {
+		t.Fatalf("expected initial firewall rules in the request, got %#v", request.FirewallRules)
+	}
+	racks := map[string]int{}
+	for _, rack := range request.RackAllocations {
+		racks[rack.Name] = rack.NodeCount
+	}
+	if len(racks) != 2 || racks["a"] != 1 || racks["b"] != 1 {
+		t.Fatalf("expected one node in racks a and b, got %#v", request.RackAllocations)
+	}
+	if id := state.Attributes["datacenter.1.datacenter_id"]; id != "dc-2" {
+		t.Fatalf("expected added datacenter to be matched to dc-2, got %q", id)
+	}
+	if network := state.Attributes["datacenter.1.default_network"]; network != "10.1.0.0/16" {
+		t.Fatalf("expected added datacenter network 10.1.0.0/16, got %q", network)
+	}
+}
+
+func TestResourceClusterDiff_changeDatacenterForcesNew(t *testing.T) {
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	new := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.2.0.0/16"))
+	diff := testClusterDiff(t, old, new)
+	if !diff.RequiresNew() {
+		t.Fatalf("expected changing an existing datacenter to force a new cluster, got %#v", diff)
+	}
+}
+
+func TestResourceClusterDiff_deletionProtectionRejectsReplacement(t *testing.T) {
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	old["deletion_protection"] = true
+	new := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.2.0.0/16"))
+	new["deletion_protection"] = true
+	_, err := testClusterDiffErr(t, old, new)
+	if err == nil || !strings.Contains(err.Error(), "deletion_protection enabled and changes to datacenter.0.default_network would replace it") {
+		t.Fatalf("expected deletion protection error, got %v", err)
+	}
+
+	// Changes applied in place are still allowed
+	resized := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+	resized["size"] = "m4.xlarge"
+	new = testClusterRawConfig(resized)
+	new["deletion_protection"] = true
+	if _, err := testClusterDiffErr(t, old, new); err != nil {
+		t.Fatalf("expected in place change to be allowed, got %s", err)
+	}
+}
+
+func TestResourceInstaclustrClusterDelete_deletionProtection(t *testing.T) {
+	raw := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	raw["deletion_protection"] = true
+	d := schema.TestResourceDataRaw(t, resourceCluster().Schema, raw)
+	d.SetId("cluster-id")
+	err := resourceInstaclustrClusterDelete(d, nil)
+	if err == nil || !strings.Contains(err.Error(), "deletion_protection enabled") {
+		t.Fatalf("expected deletion protection error, got %v", err)
+	}
+	if d.Id() != "cluster-id" {
+		t.Fatalf("expected protected cluster to stay in state")
+	}
+}
+
+func TestResourceInstaclustrClusterCreate_failedWaitKeepsID(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"cluster-id"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterStatus":"FAILED"}`))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, resourceCluster().Schema, testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")))
+	err := resourceInstaclustrClusterCreate(d, client)
+	if err == nil || !strings.Contains(err.Error(), "FAILED") {
+		t.Fatalf("expected failed wait error, got %v", err)
+	}
+	if d.Id() != "cluster-id" {
+		t.Fatalf("expected cluster to stay in state after a failed wait, got %q", d.Id())
+	}
+}
+
+func TestResourceInstaclustrClusterDelete_waits(t *testing.T) {
+	status := func(s string) string { return fmt.Sprintf(`{"id":"cluster-id","clusterStatus":"%s"}`, s) }
+	testResourceDelete(t, resourceCluster(), map[string]interface{}{}, "cluster-id", map[string]testDeleteCase{
+		"already deleted":      {http.StatusNotFound, nil},
+		"deleting":             {http.StatusAccepted, []string{status("DELETING"), status("DELETED")}},
+		"failed":               {http.StatusAccepted, []string{status("FAILED"), status("DELETED")}},
+		"removed after delete": {http.StatusAccepted, []string{status("DELETING"), ""}},
+	})
+}
+
+func TestResourceInstaclustrClusterDelete_error(t *testing.T) {
+	testResourceDeleteError(t, resourceCluster(), map[string]interface{}{}, "cluster-id")
+}
+
+func TestResourceClusterDiff_removeDatacenterForcesNew(t *testing.T) {
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"), testClusterRawDatacenter("US_WEST_2", "10.1.0.0/16"))
+	new := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	diff := testClusterDiff(t, old, new)
+	if !diff.RequiresNew() {
+		t.Fatalf("expected removing a datacenter to force a new cluster, got %#v", diff)
+	}
+}
+
+func TestResourceClusterDiff_scaleUpRackInPlace(t *testing.T) {
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	scaled := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+	scaled["rack"] = []interface{}{
+		map[string]interface{}{"name": "a", "node_count": 2},
+		map[string]interface{}{"name": "b", "node_count": 1},
+	}
+	diff := testClusterDiff(t, old, testClusterRawConfig(scaled))
+	if diff.RequiresNew() {
+		t.Fatalf("expected scaling up a rack to update in place, got %#v", diff)
+	}
+}
+
+func TestResourceClusterDiff_scaleDownRackRejected(t *testing.T) {
+	old := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+	old["rack"] = []interface{}{
+		map[string]interface{}{"name": "a", "node_count": 2},
+		map[string]interface{}{"name": "b", "node_count": 2},
+	}
+	scaled := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+	scaled["rack"] = []interface{}{
+		map[string]interface{}{"name": "a", "node_count": 1},
+		map[string]interface{}{"name": "b", "node_count": 2},
+	}
+	_, err := testClusterDiffErr(t, testClusterRawConfig(old), testClusterRawConfig(scaled))
+	if err == nil || !strings.Contains(err.Error(), "scaling down is not supported") {
+		t.Fatalf("expected scale down error, got %v", err)
+	}
+}
+
+func TestResourceClusterDiff_resizeInPlace(t *testing.T) {
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	resized := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+	resized["size"] = "m4.xlarge"
+	diff := testClusterDiff(t, old, testClusterRawConfig(resized))
+	if diff.RequiresNew() {
+		t.Fatalf("expected changing size to update in place, got %#v", diff)
+	}
+}
+
+func TestResourceClusterDiff_validatesDatacenter(t *testing.T) {
+	cases := []struct {
+		Field string
+		Value interface{}
+		Error string
+	}{
+		{"default_network", "10.0.0.0/24", "datacenter.0.default_network: \"10.0.0.0/24\" must have a prefix length between /12 and /22"},
+		{"default_network", "8.8.0.0/16", "datacenter.0.default_network: \"8.8.0.0/16\" must be within a private network"},
+		{"default_network", "10.0.0.0", "datacenter.0.default_network: \"10.0.0.0\" is not a valid IPv4 CIDR block"},
+		{"rack", []interface{}{
+			map[string]interface{}{"name": "a", "node_count": 1},
+			map[string]interface{}{"name": "a", "node_count": 2},
+		}, "datacenter.0.rack: rack name \"a\" is used more than once"},
+		{"disk_encryption_key", "0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11", "datacenter.0.disk_encryption_key: disk encryption is not supported on t2 sizes"},
+		{"size", "Standard_DS2_v2-256", "datacenter.0.size: size \"Standard_DS2_v2-256\" is for AZURE and can't be used with provider_name AWS_VPC"},
+	}
+	for _, tc := range cases {
+		dc := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+		dc[tc.Field] = tc.Value
+		_, err := testClusterDiffErr(t, testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")), testClusterRawConfig(dc))
+		if err == nil || !strings.Contains(err.Error(), tc.Error) {
+			t.Fatalf("%s = %v: expected error %q, got %v", tc.Field, tc.Value, tc.Error, err)
+		}
+	}
+}
+
+func TestValidateClusterNetwork(t *testing.T) {
+	for _, network := range []string{"10.0.0.0/16", "172.16.0.0/12", "192.168.0.0/22"} {
+		if err := validateClusterNetwork(network); err != nil {
+			t.Fatalf("expected %s to be valid, got %s", network, err)
+		}
+	}
+	for _, network := range []string{"10.0.0.0/8", "172.32.0.0/16", "fd00::/16"} {
+		if err := validateClusterNetwork(network); err == nil {
+			t.Fatalf("expected %s to be invalid", network)
+		}
+	}
+}
+
+func TestDatacenterClientEncryption(t *testing.T) {
+	key := "0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11"
+	cases := []struct {
+		ClientEncryption  interface{}
+		DiskEncryptionKey string
+		Expected          bool
+	}{
+		{nil, "", false},
+		{nil, key, true},
+		{false, key, false},
+		{true, "", true},
+	}
+	for _, tc := range cases {
+		dc := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+		if tc.ClientEncryption != nil {
+			dc["client_encryption"] = tc.ClientEncryption
+		}
+		if tc.DiskEncryptionKey != "" {
+			dc["disk_encryption_key"] = tc.DiskEncryptionKey
+		}
+		d := schema.TestResourceDataRaw(t, resourceCluster().Schema, testClusterRawConfig(dc))
+		if v := datacenterClientEncryption(d, 0); v != tc.Expected {
+			t.Fatalf("client_encryption %v, disk_encryption_key %q: expected %t, got %t", tc.ClientEncryption, tc.DiskEncryptionKey, tc.Expected, v)
+		}
+	}
+}
+
+func TestDatacenterSize_interruptedResize(t *testing.T) {
+	datacenter := Datacenter{
+		ID: "dc",
+		Nodes: []DatacenterNode{
+			DatacenterNode{Size: "m4.xlarge"},
+			DatacenterNode{Size: "t2.small"},
+		},
+	}
+	if size := datacenterSize(datacenter, "m4.xlarge"); size != "t2.small" {
+		t.Fatalf("expected mixed sizes to report the unresized size, got %s", size)
+	}
+	datacenter.Nodes[1].Size = "m4.xlarge"
+	if size := datacenterSize(datacenter, "m4.xlarge"); size != "m4.xlarge" {
+		t.Fatalf("expected m4.xlarge, got %s", size)
+	}
+}
+
+func TestValidateVersionUpgrade(t *testing.T) {
+	cases := []struct {
+		From    string
+		To      string
+		Replace bool
+		Err     bool
+	}{
+		{"apache-cassandra-3.0.10", "apache-cassandra-3.0.18", false, false},
+		{"apache-cassandra-3.0.10", "apache-cassandra-3.11.4", false, false},
+		{"apache-cassandra-2.2.13", "apache-cassandra-3.0.18", false, false},
+		{"apache-cassandra-3.0.18", "apache-cassandra-3.0.10", false, true},
+		{"apache-cassandra-2.2.13", "apache-cassandra-4.0.0", false, true},
+		{"apache-cassandra-3.0.10", "dse-5.1.0", true, false},
+	}
+	for _, tc := range cases {
+		replace, err := validateVersionUpgrade(tc.From, tc.To)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s -> %s: expected error %t, got %v", tc.From, tc.To, tc.Err, err)
+		}
+		if replace != tc.Replace {
+			t.Fatalf("%s -> %s: expected replace %t, got %t", tc.From, tc.To, tc.Replace, replace)
+		}
+	}
+}
+
+func TestResourceClusterDiff_upgradeInPlace(t *testing.T) {
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	upgraded := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	upgraded["version"] = "apache-cassandra-3.0.18"
+	diff := testClusterDiff(t, old, upgraded)
+	if diff.RequiresNew() {
+		t.Fatalf("expected a version upgrade to update in place, got %#v", diff)
+	}
+}
+
+func TestResourceClusterDiff_defaultTags(t *testing.T) {
+	old := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	tagged := testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16"))
+	tagged["tags"] = map[string]interface{}{"team": "data", "env": "prod"}
+	meta := &InstaclustrClient{config: Config{DefaultTags: map[string]string{"env": "dev", "owner": "platform"}}}
+	diff, err := testClusterDiffMeta(t, old, tagged, meta)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff.RequiresNew() {
+		t.Fatalf("expected tags to update in place, got %#v", diff)
+	}
+	expected := map[string]string{"tags_all.env": "prod", "tags_all.owner": "platform", "tags_all.team": "data"}
+	for k, v := range expected {
+		if diff.Attributes[k] == nil || diff.Attributes[k].New != v {
+			t.Fatalf("expected %s to be %s, got %#v", k, v, diff.Attributes[k])
+		}
+	}
+}
+
+func TestResourceTags_removesDefaults(t *testing.T) {
+	tags := map[string]string{"env": "prod", "owner": "platform", "team": "data"}
+	defaults := map[string]string{"env": "prod", "owner": "platform"}
+	configured := map[string]interface{}{"env": "prod", "team": "data"}
+	result := resourceTags(tags, defaults, configured)
+	if len(result) != 2 || result["env"] != "prod" || result["team"] != "data" {
+		t.Fatalf("expected only configured tags, got %#v", result)
+	}
+}
+
+func TestResourceInstaclustrClusterUpdate_initialFirewallRulesCreateOnly(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/cluster-id" {
+			t.Errorf("unexpected %s request to %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterName":"terraform-test","cassandraVersion":"apache-cassandra-3.0.10",
+			"clusterStatus":"RUNNING","clusterNetwork":{"network":"10.0.0.0","prefixLength":16},"dataCentres":[
+			{"id":"dc-1","name":"US_EAST_1","provider":"AWS_VPC","nodes":[
+			{"id":"node-1","size":"t2.small","rack":"a","nodeStatus":"RUNNING"},
+			{"id":"node-2","size":"t2.small","rack":"b","nodeStatus":"RUNNING"}]}]}`))
+	})
+	defer done()
+
+	oldDatacenter := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+	oldDatacenter["initial_firewall_rules"] = []interface{}{"10.1.0.0/16"}
+	newDatacenter := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+	newDatacenter["initial_firewall_rules"] = []interface{}{"10.1.0.0/16", "10.2.0.0/16"}
+	state, err := testClusterApply(t, testClusterRawConfig(oldDatacenter), testClusterRawConfig(newDatacenter), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state.Attributes["datacenter.0.initial_firewall_rules.#"] != "2" || state.Attributes["datacenter.0.initial_firewall_rules.1"] != "10.2.0.0/16" {
+		t.Fatalf("expected configured initial firewall rules to be kept, got %#v", state.Attributes)
+	}
+}
+
+func TestResourceClusterImport_planIsClean(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterName":"terraform-test","cassandraVersion":"apache-cassandra-3.0.10",
+			"clusterStatus":"RUNNING","clusterNetwork":{"network":"10.0.0.0","prefixLength":16},"dataCentres":[
+			{"id":"dc-1","name":"US_EAST_1","provider":"AWS_VPC","providerAccountName":"INSTACLUSTR",
+			"encryptionKeyId":"0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11","clientEncryption":true,
+			"passwordAuthentication":true,"userAuthorization":true,"usePrivateBroadcastRPCAddress":true,"nodes":[
+			{"id":"node-1","size":"m4.xlarge","rack":"a","privateAddress":"10.0.0.1","nodeStatus":"RUNNING"},
+			{"id":"node-2","size":"m4.xlarge","rack":"b","privateAddress":"10.0.0.2","nodeStatus":"RUNNING"}]}]}`))
+	})
+	defer done()
+
+	r := resourceCluster()
+	d := r.TestResourceData()
+	d.SetId("cluster-id")
+	imported, err := resourceInstaclustrClusterImport(d, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := resourceInstaclustrClusterRead(imported[0], client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dc := testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")
+	dc["size"] = "m4.xlarge"
+	dc["account"] = "INSTACLUSTR"
+	dc["auth"] = true
+	dc["disk_encryption_key"] = "arn:aws:kms:us-east-1:123456789012:key/0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11"
+	c, err := config.NewRawConfig(testClusterRawConfig(dc))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	diff, err := r.Diff(imported[0].State(), terraform.NewResourceConfig(c), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff != nil && len(diff.Attributes) > 0 {
+		for k, attr := range diff.Attributes {
+			t.Errorf("unexpected diff for %s: %#v", k, attr)
+		}
+		t.Fatalf("expected no diff after import")
+	}
+}
+
+func TestResourceClusterRead_noDatacentersYet(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterName":"terraform-test","clusterStatus":"GENESIS","dataCentres":[]}`))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, resourceCluster().Schema, testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")))
+	d.SetId("cluster-id")
+	if err := resourceInstaclustrClusterRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "cluster-id" {
+		t.Fatalf("expected cluster to stay in state")
+	}
+	if n := len(d.Get("datacenter").([]interface{})); n != 1 {
+		t.Fatalf("expected configured datacenter to be kept, got %d", n)
+	}
+}
+
+func TestResourceClusterRead_deleted(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster-id","clusterName":"terraform-test","clusterStatus":"DELETED"}`))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, resourceCluster().Schema, testClusterRawConfig(testClusterRawDatacenter("US_EAST_1", "10.0.0.0/16")))
+	d.SetId("cluster-id")
+	if err := resourceInstaclustrClusterRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected deleted cluster to be removed from state")
+	}
+}
+
+func TestClusterFailure(t *testing.T) {
+	cluster := &ClusterStatus{
+		ID:            "cluster",
+		ClusterStatus: "PROVISIONING",
+		Datacenters: []Datacenter{
+			Datacenter{Nodes: []DatacenterNode{
+				DatacenterNode{ID: "node-1", NodeStatus: "RUNNING"},
+				DatacenterNode{ID: "node-2", NodeStatus: "PROVISIONING"},
+			}},
+		},
+	}
+	if err := clusterFailure(cluster); err != nil {
+		t.Fatalf("expected provisioning cluster to not be failed, got %s", err)
+	}
+	cluster.Datacenters[0].Nodes[1].NodeStatus = "FAILED"
+	err := clusterFailure(cluster)
+	if err == nil || !strings.Contains(err.Error(), "node-2 (FAILED)") {
+		t.Fatalf("expected failed node error, got %v", err)
+	}
+}
+
+func TestClusterNodesStateRefreshFunc(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"cluster","clusterStatus":"RUNNING","dataCentres":[
+			{"id":"dc-1","nodes":[{"id":"node-1","nodeStatus":"RUNNING"},{"id":"node-2","nodeStatus":"PROVISIONING"}]},
+			{"id":"dc-2","nodes":[{"id":"node-3","nodeStatus":"RUNNING"}]}]}`))
+	})
+	defer done()
+
+	_, state, err := clusterNodesStateRefreshFunc(client.ClusterClient(), "cluster", 3)()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state != "PROVISIONING" {
+		t.Fatalf("expected PROVISIONING with 2/3 nodes running, got %s", state)
+	}
+	_, state, err = clusterNodesStateRefreshFunc(client.ClusterClient(), "cluster", 2)()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state != "RUNNING" {
+		t.Fatalf("expected RUNNING with 2/2 nodes running, got %s", state)
+	}
+}
+
+func TestAddNodes(t *testing.T) {
+	node := func(id, rack, status string) string {
+		return fmt.Sprintf(`{"id":"%s","rack":"%s","nodeStatus":"%s"}`, id, rack, status)
+	}
+	cluster := func(nodes ...string) string {
+		return fmt.Sprintf(`{"id":"cluster-id","clusterStatus":"RUNNING","dataCentres":[{"id":"dc-1","nodes":[%s]},
+			{"id":"dc-2","nodes":[%s,%s]}]}`, strings.Join(nodes, ","), node("node-5", "a", "RUNNING"), node("node-6", "c", "RUNNING"))
+	}
+	var request AddNodesRequest
+	posts, gets := 0, 0
+	client, done := testClient(testSequenceHandler([]string{
+		cluster(node("node-1", "a", "RUNNING"), node("node-2", "b", "RUNNING"), node("node-3", "a", "PROVISIONING"), node("node-4", "c", "PROVISIONING")),
+		cluster(node("node-1", "a", "RUNNING"), node("node-2", "b", "RUNNING"), node("node-3", "a", "RUNNING"), node("node-4", "c", "PROVISIONING")),
+		cluster(node("node-1", "a", "RUNNING"), node("node-2", "b", "RUNNING"), node("node-3", "a", "RUNNING"), node("node-4", "c", "RUNNING")),
+	}, &gets, func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		if r.URL.Path != "/cluster-id/cluster-data-centres/dc-1/nodes" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer done()
+
+	err := addNodes(client.ClusterClient(), "cluster-id", "dc-1", map[string]int{"a": 1, "b": 1}, map[string]int{"a": 2, "b": 1, "c": 1}, time.Minute)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if posts != 1 {
+		t.Fatalf("expected one add nodes request, got %d", posts)
+	}
+	racks := map[string]int{}
+	for _, rack := range request.RackAllocations {
+		racks[rack.Name] = rack.NodeCount
+	}
+	if len(racks) != 2 || racks["a"] != 1 || racks["c"] != 1 {
+		t.Fatalf("expected one node added to racks a and c, got %#v", request.RackAllocations)
+	}
+	if gets != 3 {
+		t.Fatalf("expected to wait until every rack was running, got %d polls", gets)
+	}
+}
+
+func TestResizeDatacenter(t *testing.T) {
+	node := func(id, size, status string) string {
+		return fmt.Sprintf(`{"id":"%s","size":"%s","nodeStatus":"%s"}`, id, size, status)
+	}
+	cluster := func(nodes ...string) string {
+		return fmt.Sprintf(`{"id":"cluster-id","clusterStatus":"RUNNING","dataCentres":[{"id":"dc-1","nodes":[%s]},
+			{"id":"dc-2","nodes":[%s]}]}`, strings.Join(nodes, ","), node("node-3", "m4.large", "RUNNING"))
+	}
+	var request ResizeDatacenterRequest
+	gets := 0
+	client, done := testClient(testSequenceHandler([]string{
+		cluster(node("node-1", "m4.large", "RUNNING"), node("node-2", "m4.large", "RUNNING")),
+		cluster(node("node-1", "m4.xlarge", "RESIZING"), node("node-2", "m4.large", "RUNNING")),
+		cluster(node("node-1", "m4.xlarge", "RUNNING"), node("node-2", "m4.xlarge", "RESIZING")),
+		cluster(node("node-1", "m4.xlarge", "RUNNING"), node("node-2", "m4.xlarge", "RUNNING")),
+	}, &gets, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cluster-id/cluster-data-centres/dc-1/resize" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"operationId":"operation-id"}`))
+	}))
+	defer done()
+
+	err := resizeDatacenter(client.ClusterClient(), "cluster-id", "dc-1", "m4.xlarge", 2, time.Minute)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if request.NewNodeSize != "m4.xlarge" || request.ConcurrentResizes != 2 {
+		t.Fatalf("expected resize to m4.xlarge two nodes at a time, got %#v", request)
+	}
+	if gets != 4 {
+		t.Fatalf("expected to wait until every node was running the new size, got %d polls", gets)
+	}
+}
+
+func TestUpgradeCluster(t *testing.T) {
+	cluster := func(version, status string) string {
+		return fmt.Sprintf(`{"id":"cluster-id","clusterStatus":"RUNNING","cassandraVersion":"%s","dataCentres":[
+			{"id":"dc-1","nodes":[{"id":"node-1","nodeStatus":"RUNNING"},{"id":"node-2","nodeStatus":"%s"}]}]}`, version, status)
+	}
+	var request UpgradeClusterRequest
+	gets := 0
+	client, done := testClient(testSequenceHandler([]string{
+		cluster("apache-cassandra-3.0.10", "RUNNING"),
+		cluster("apache-cassandra-3.11.4", "PROVISIONING"),
+		cluster("apache-cassandra-3.11.4", "RUNNING"),
+	}, &gets, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/cluster-id/software-upgrade" {
+			t.Errorf("unexpected %s request to %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer done()
+
+	err := upgradeCluster(client.ClusterClient(), "cluster-id", "apache-cassandra-3.11.4", time.Minute)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if request.Version != "apache-cassandra-3.11.4" {
+		t.Fatalf("expected upgrade to apache-cassandra-3.11.4, got %q", request.Version)
+	}
+	if gets != 3 {
+		t.Fatalf("expected to wait until the new version was running on every node, got %d polls", gets)
+	}
+}
+
+// testClusterDiff diffs the new raw config against state built from the old raw config
+func testClusterDiff(t *testing.T, old, new map[string]interface{}) *terraform.InstanceDiff {
+	diff, err := testClusterDiffErr(t, old, new)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return diff
+}
+
+func testClusterDiffErr(t *testing.T, old, new map[string]interface{}) (*terraform.InstanceDiff, error) {
+	return testClusterDiffMeta(t, old, new, nil)
+}
+
+func testClusterDiffMeta(t *testing.T, old, new map[string]interface{}, meta interface{}) (*terraform.InstanceDiff, error) {
+	r := resourceCluster()
+	d := schema.TestResourceDataRaw(t, r.Schema, old)
+	d.SetId("cluster-id")
+	state := d.State()
+	c, err := config.NewRawConfig(new)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return r.Diff(state, terraform.NewResourceConfig(c), meta)
+}
+
+// testClusterApply applies the new raw config to state built from the old raw config
+func testClusterApply(t *testing.T, old, new map[string]interface{}, meta interface{}) (*terraform.InstanceState, error) {
+	r := resourceCluster()
+	d := schema.TestResourceDataRaw(t, r.Schema, old)
+	d.SetId("cluster-id")
+	state := d.State()
+	c, err := config.NewRawConfig(new)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	diff, err := r.Diff(state, terraform.NewResourceConfig(c), meta)
+	if err != nil {
+		return nil, err
+	}
+	return r.Apply(state, diff, meta)
+}
+
+func testClusterRawConfig(datacenters ...map[string]interface{}) map[string]interface{} {
+	dcs := []interface{}{}
+	for _, dc := range datacenters {
+		dcs = append(dcs, dc)
+	}
+	return map[string]interface{}{
+		"name":       "terraform-test",
+		"version":    "apache-cassandra-3.0.10",
+		"datacenter": dcs,
+	}
+}
+
+func testClusterRawDatacenter(region, network string) map[string]interface{} {
+	return map[string]interface{}{
+		"provider_name":   "AWS_VPC",
+		"region":          region,
+		"size":            "t2.small",
+		"default_network": network,
+		"rack": []interface{}{
+			map[string]interface{}{"name": "a", "node_count": 1},
+			map[string]interface{}{"name": "b", "node_count": 1},
+		},
+	}
+}
+
 func testAccCheckInstaclustrClusterExists(n string, c *ClusterStatus) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -55,10 +778,15 @@ func testAccCheckInstaclustrClusterDestroy(s *terraform.State) error {
 			continue
 		}
 		cluster, err := client.Get(rs.Primary.ID)
-		if err == nil {
-			if cluster != nil && cluster.ID == rs.Primary.ID {
-				return fmt.Errorf("Cluster still exists")
+		if err != nil {
+			if IsNotFound(err) {
+				continue
 			}
+			return err
+		}
+		// Deleted clusters may still be returned until they are purged
+		if cluster != nil && cluster.ID == rs.Primary.ID && cluster.ClusterStatus != "DELETED" {
+			return fmt.Errorf("Cluster still exists")
 		}
 	}
 	return nil
@@ -85,3 +813,40 @@ resource "instaclustr_cluster" "foo" {
   }  
 }
 `
+
+const testAccInstaclustrClusterMultiDatacenterConfig = `
+resource "instaclustr_cluster" "foo" {
+  name = "terraform-test-acc"
+  version = "apache-cassandra-3.0.10"
+  datacenter {
+    provider_name = "AWS_VPC"
+    account = "PeopleNet"
+    region = "US_EAST_1"
+    size = "t2.small"
+    default_network = "10.0.0.0/16"
+    rack {
+      name = "us-east-1a"
+      node_count = 1
+    }
+    rack {
+      name = "us-east-1b"
+      node_count = 1
+    }
+  }
+  datacenter {
+    provider_name = "AWS_VPC"
+    account = "PeopleNet"
+    region = "US_WEST_2"
+    size = "t2.small"
+    default_network = "10.1.0.0/16"
+    rack {
+      name = "us-west-2a"
+      node_count = 1
+    }
+    rack {
+      name = "us-west-2b"
+      node_count = 1
+    }
+  }
+}
+`
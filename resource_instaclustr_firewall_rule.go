@@ -3,30 +3,70 @@ package main
 import (
 	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
+// firewallRuleTypes are the services a firewall rule can authorize access to
+var firewallRuleTypes = []string{
+	"CASSANDRA",
+	"CASSANDRA_THRIFT",
+	"SPARK",
+	"SPARK_JOBSERVER",
+	"KAFKA",
+	"KAFKA_CONNECT",
+	"ELASTICSEARCH",
+	"KIBANA",
+	"ZEPPELIN",
+}
+
 func resourceFirewallRule() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceInstaclustrFirewallRuleCreate,
-		Read:   resourceInstaclustrFirewallRuleRead,
-		Delete: resourceInstaclustrFirewallRuleDelete,
+		Create:        resourceInstaclustrFirewallRuleCreate,
+		Read:          resourceInstaclustrFirewallRuleRead,
+		Update:        resourceInstaclustrFirewallRuleUpdate,
+		Delete:        resourceInstaclustrFirewallRuleDelete,
+		CustomizeDiff: resourceInstaclustrFirewallRuleCustomizeDiff,
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		Timeouts: &schema.ResourceTimeout{
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"network": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"security_group_id"},
+				ValidateFunc:  validateCIDR,
+			},
+			"security_group_id": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"network"},
+				ValidateFunc:  validateSecurityGroupID,
 			},
 			"cluster_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateUUID,
+			},
+			"rule_types": &schema.Schema{
+				Type:     schema.TypeSet,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: stringInList(firewallRuleTypes),
+				},
 			},
 		},
 	}
@@ -34,67 +74,171 @@ func resourceFirewallRule() *schema.Resource {
 
 func resourceInstaclustrFirewallRuleCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*InstaclustrClient).FirewallClient()
-	network := d.Get("network").(string)
+	source := firewallRuleSource(d)
 	clusterID := d.Get("cluster_id").(string)
-	err := client.Create(clusterID, network)
+	ruleTypes := expandStringSet(d.Get("rule_types").(*schema.Set))
+	if len(ruleTypes) == 0 {
+		ruleTypes = []string{"CASSANDRA"}
+	}
+	err := client.Create(clusterID, source, ruleTypes)
 	if err != nil {
 		d.SetId("")
 		return err
 	}
-	d.SetId(firewallID(clusterID, network))
+	d.SetId(firewallID(clusterID, source))
 	return resourceInstaclustrFirewallRuleRead(d, m)
 }
 
 func resourceInstaclustrFirewallRuleRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*InstaclustrClient).FirewallClient()
-	clusterID, network, err := splitFirewallID(d.Id())
+	clusterID, source, err := splitFirewallID(d.Id())
 	if err != nil {
 		d.SetId("")
 		return err
 	}
 	firewallRules, err := client.List(clusterID)
 	if err != nil {
+		if IsNotFound(err) {
+			log.Printf("[WARN] Cluster (%s) for Firewall Rule (%s) not found, removing from state", clusterID, d.Id())
+			d.SetId("")
+			return nil
+		}
 		return err
 	}
-	var networkRule *Firewall
+	var sourceRule *Firewall
 	for _, f := range firewallRules {
-		if f.Network == network {
-			networkRule = f
+		if f.Source() == source {
+			sourceRule = f
 		}
 	}
-	if networkRule == nil {
+	if sourceRule == nil {
+		log.Printf("[WARN] Firewall Rule (%s) not found, removing from state", d.Id())
 		d.SetId("")
 	} else {
-		d.Set("network", networkRule.Network)
+		d.Set("network", sourceRule.Network)
+		d.Set("security_group_id", sourceRule.SecurityGroupID)
 		d.Set("cluster_id", clusterID)
-		d.SetId(firewallID(clusterID, networkRule.Network))
+		d.Set("rule_types", firewallRuleTypeNames(sourceRule.Rules))
+		d.SetId(firewallID(clusterID, sourceRule.Source()))
 	}
 	return nil
 }
 
+func resourceInstaclustrFirewallRuleUpdate(d *schema.ResourceData, m interface{}) error {
+	client := m.(*InstaclustrClient).FirewallClient()
+	clusterID, source, err := splitFirewallID(d.Id())
+	if err != nil {
+		return err
+	}
+	if d.HasChange("rule_types") {
+		o, n := d.GetChange("rule_types")
+		added := expandStringSet(n.(*schema.Set).Difference(o.(*schema.Set)))
+		removed := expandStringSet(o.(*schema.Set).Difference(n.(*schema.Set)))
+		if len(added) > 0 {
+			err = client.Create(clusterID, source, added)
+			if err != nil {
+				return err
+			}
+		}
+		if len(removed) > 0 {
+			err = client.Delete(clusterID, source, removed)
+			if err != nil && !IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return resourceInstaclustrFirewallRuleRead(d, m)
+}
+
 func resourceInstaclustrFirewallRuleDelete(d *schema.ResourceData, m interface{}) error {
 	client := m.(*InstaclustrClient).FirewallClient()
-	clusterID, network, err := splitFirewallID(d.Id())
+	clusterID, source, err := splitFirewallID(d.Id())
 	if err != nil {
 		d.SetId("")
 		return err
 	}
-	err = client.Delete(clusterID, network)
+	ruleTypes := expandStringSet(d.Get("rule_types").(*schema.Set))
+	if len(ruleTypes) == 0 {
+		ruleTypes = []string{"CASSANDRA"}
+	}
+	err = client.Delete(clusterID, source, ruleTypes)
 	if err != nil {
+		if IsNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+	err = resource.Retry(d.Timeout(schema.TimeoutDelete), func() *resource.RetryError {
+		firewallRules, err := client.List(clusterID)
+		if err != nil {
+			if IsNotFound(err) {
+				return nil
+			}
+			return resource.NonRetryableError(err)
+		}
+		// Other rule types on the same source may be managed elsewhere, so only
+		// wait for the deleted types to go
+		for _, f := range firewallRules {
+			if f.Source() != source {
+				continue
+			}
+			remaining := []string{}
+			for _, ruleType := range firewallRuleTypeNames(f.Rules) {
+				if stringInSlice(ruleType, ruleTypes) {
+					remaining = append(remaining, ruleType)
+				}
+			}
+			if len(remaining) > 0 {
+				return resource.RetryableError(fmt.Errorf("Firewall Rule (%s) still has %s", d.Id(), strings.Join(remaining, ", ")))
+			}
+		}
 		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Error waiting for Firewall Rule (%s) to be deleted: %s", d.Id(), err)
 	}
 	d.SetId("")
 	return nil
 }
 
-func firewallID(clusterID, network string) string {
-	return fmt.Sprintf("%s:%s", clusterID, network)
+// resourceInstaclustrFirewallRuleCustomizeDiff requires exactly one of network or security_group_id
+func resourceInstaclustrFirewallRuleCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("network") || !d.NewValueKnown("security_group_id") {
+		return nil
+	}
+	_, hasNetwork := d.GetOk("network")
+	_, hasSecurityGroup := d.GetOk("security_group_id")
+	if !hasNetwork && !hasSecurityGroup {
+		return errors.New("one of network or security_group_id must be set")
+	}
+	return nil
+}
+
+// firewallRuleSource returns the network CIDR or security group ID of the rule
+func firewallRuleSource(d *schema.ResourceData) string {
+	if securityGroupID, ok := d.GetOk("security_group_id"); ok {
+		return securityGroupID.(string)
+	}
+	return d.Get("network").(string)
+}
+
+func firewallID(clusterID, source string) string {
+	return fmt.Sprintf("%s:%s", clusterID, source)
 }
 
 func splitFirewallID(id string) (string, string, error) {
 	tokens := strings.Split(id, ":")
-	if len(tokens) != 2 {
-		return "", "", errors.New("Must supply ID in format of <clusterDatacenterID>:<network>")
+	if len(tokens) != 2 || tokens[1] == "" {
+		return "", "", errors.New("Must supply ID in format of <clusterID>:<network> or <clusterID>:<securityGroupID>")
 	}
 	return tokens[0], tokens[1], nil
 }
+
+func firewallRuleTypeNames(rules []FirewallRule) []string {
+	names := []string{}
+	for _, rule := range rules {
+		names = append(names, rule.Type)
+	}
+	return names
+}
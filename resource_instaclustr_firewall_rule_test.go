@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
@@ -20,12 +21,66 @@ func TestAccInstaclustrFirewallRule_basic(t *testing.T) {
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckInstaclustrFirewallRuleExists("instaclustr_firewall_rule.foo", &firewall),
 					resource.TestCheckResourceAttr("instaclustr_firewall_rule.foo", "network", "10.1.0.0/16"),
+					resource.TestCheckResourceAttr("instaclustr_firewall_rule.foo", "rule_types.#", "1"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccInstaclustrFirewallRuleRuleTypesConfig,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstaclustrFirewallRuleExists("instaclustr_firewall_rule.foo", &firewall),
+					resource.TestCheckResourceAttr("instaclustr_firewall_rule.foo", "rule_types.#", "2"),
 				),
 			},
 		},
 	})
 }
 
+func TestSplitFirewallID(t *testing.T) {
+	cases := map[string]string{
+		"cluster:10.1.0.0/16": "10.1.0.0/16",
+		"cluster:sg-12345678": "sg-12345678",
+	}
+	for id, source := range cases {
+		clusterID, s, err := splitFirewallID(id)
+		if err != nil {
+			t.Fatalf("%s: err: %s", id, err)
+		}
+		if clusterID != "cluster" || s != source {
+			t.Fatalf("%s: expected cluster and %s, got %s and %s", id, source, clusterID, s)
+		}
+		if firewall := newFirewall(s, nil); firewall.Source() != source {
+			t.Fatalf("%s: expected firewall source %s, got %#v", id, source, firewall)
+		}
+	}
+	if _, _, err := splitFirewallID("cluster"); err == nil {
+		t.Fatal("expected error for ID without a source")
+	}
+	if _, _, err := splitFirewallID("cluster:2001:db8::/32"); err == nil {
+		t.Fatal("expected error for ID with an IPv6 source")
+	}
+}
+
+func TestResourceInstaclustrFirewallRuleDelete_waits(t *testing.T) {
+	cassandra := `[{"network":"10.1.0.0/16","rules":[{"type":"CASSANDRA"}]}]`
+	testResourceDelete(t, resourceFirewallRule(), testFirewallRuleRawConfig, firewallID("cluster-id", "10.1.0.0/16"), map[string]testDeleteCase{
+		"already deleted":         {http.StatusNotFound, nil},
+		"removed":                 {http.StatusAccepted, []string{cassandra, `[]`}},
+		"cluster deleted":         {http.StatusAccepted, []string{cassandra, ""}},
+		"other rule types remain": {http.StatusAccepted, []string{`[{"network":"10.1.0.0/16","rules":[{"type":"KAFKA"}]}]`}},
+	})
+}
+
+func TestResourceInstaclustrFirewallRuleDelete_error(t *testing.T) {
+	testResourceDeleteError(t, resourceFirewallRule(), testFirewallRuleRawConfig, firewallID("cluster-id", "10.1.0.0/16"))
+}
+
+// testFirewallRuleRawConfig is a CASSANDRA rule for 10.1.0.0/16
+var testFirewallRuleRawConfig = map[string]interface{}{
+	"cluster_id": "cluster-id",
+	"network":    "10.1.0.0/16",
+	"rule_types": []interface{}{"CASSANDRA"},
+}
+
 func testAccCheckInstaclustrFirewallRuleExists(n string, fw *Firewall) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -101,3 +156,31 @@ resource "instaclustr_firewall_rule" "foo" {
   network = "10.1.0.0/16"
 }
 `
+
+const testAccInstaclustrFirewallRuleRuleTypesConfig = `
+resource "instaclustr_cluster" "foo" {
+  name = "terraform-test-acc"
+  version = "apache-cassandra-3.0.10"
+  datacenter {
+    provider_name = "AWS_VPC"
+    account = "PeopleNet"
+    region = "US_EAST_1"
+    size = "t2.small"
+    default_network = "10.0.0.0/16"
+    rack {
+      name = "us-east-1a"
+      node_count = 1
+    }
+    rack {
+      name = "us-east-1b"
+      node_count = 1
+    }
+  }
+}
+
+resource "instaclustr_firewall_rule" "foo" {
+  cluster_id = "${instaclustr_cluster.foo.id}"
+  network = "10.1.0.0/16"
+  rule_types = ["CASSANDRA", "CASSANDRA_THRIFT"]
+}
+`
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -10,6 +11,9 @@ import (
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
+// vpcPeeringFailureStates are the connection statuses that end a create with an error
+var vpcPeeringFailureStates = []string{"failed", "rejected", "expired", "deleted"}
+
 func resourceVpcPeeringConnection() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceInstaclustrVpcPeeringConnectionCreate,
@@ -18,27 +22,35 @@ func resourceVpcPeeringConnection() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(15 * time.Minute),
+			Delete: schema.DefaultTimeout(15 * time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"peer_vpc_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateVpcID,
 			},
 			"peer_account_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateAwsAccountID,
 			},
 			"peer_subnet": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateCIDR,
 			},
 			"cluster_datacenter_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateUUID,
 			},
 			"vpc_id": &schema.Schema{
 				Type:     schema.TypeString,
@@ -69,20 +81,20 @@ func resourceInstaclustrVpcPeeringConnectionCreate(d *schema.ResourceData, m int
 		d.SetId("")
 		return err
 	}
+	d.SetId(vpcPeeringConnectionID(clusterDatacenterID, response.ID))
 	stateConf := &resource.StateChangeConf{
 		Pending:    []string{"initiating-request", "pending-acceptance", "provisioning", "active"},
 		Target:     []string{"pending-acceptance", "active"},
 		Refresh:    vpcConnectionStateRefreshFunc(client, clusterDatacenterID, response.ID),
-		Timeout:    15 * time.Minute,
-		Delay:      3 * time.Second,
-		MinTimeout: 3 * time.Second,
+		Timeout:    d.Timeout(schema.TimeoutCreate),
+		Delay:      waitPollInterval,
+		MinTimeout: waitPollInterval,
 	}
 	_, waitErr := stateConf.WaitForState()
 	if waitErr != nil {
 		return fmt.Errorf(
 			"Error waiting for VPC Peering Connecting (%s) to be ready: %s", response.ID, waitErr)
 	}
-	d.SetId(vpcPeeringConnectionID(clusterDatacenterID, response.ID))
 	return resourceInstaclustrVpcPeeringConnectionRead(d, m)
 }
 
@@ -95,7 +107,11 @@ func resourceInstaclustrVpcPeeringConnectionRead(d *schema.ResourceData, m inter
 	}
 	vpcPeer, err := client.Get(clusterDatacenterID, id)
 	if err != nil {
-		d.SetId("")
+		if IsNotFound(err) {
+			log.Printf("[WARN] VPC Peering Connection (%s) not found, removing from state", d.Id())
+			d.SetId("")
+			return nil
+		}
 		return err
 	}
 	d.Set("peer_vpc_id", vpcPeer.PeerVpcID)
@@ -117,7 +133,24 @@ func resourceInstaclustrVpcPeeringConnectionDelete(d *schema.ResourceData, m int
 	}
 	err = client.Delete(clusterDatacenterID, id)
 	if err != nil {
-		return nil
+		if IsNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+	stateConf := &resource.StateChangeConf{
+		Pending:    []string{"initiating-request", "pending-acceptance", "provisioning", "active", "deleting"},
+		Target:     []string{"deleted", "rejected", "failed", "expired"},
+		Refresh:    vpcConnectionDeleteStateRefreshFunc(client, clusterDatacenterID, id),
+		Timeout:    d.Timeout(schema.TimeoutDelete),
+		Delay:      waitPollInterval,
+		MinTimeout: waitPollInterval,
+	}
+	_, waitErr := stateConf.WaitForState()
+	if waitErr != nil {
+		return fmt.Errorf(
+			"Error waiting for VPC Peering Connection (%s) to be deleted: %s", id, waitErr)
 	}
 	d.SetId("")
 	return nil
@@ -141,6 +174,23 @@ func vpcConnectionStateRefreshFunc(client *VpcPeeringClient, datacenterID, conne
 		if err != nil {
 			return nil, "", err
 		}
+		if stringInSlice(connection.StatusCode, vpcPeeringFailureStates) {
+			return nil, "", fmt.Errorf("VPC Peering Connection (%s) is %s: %s", connectionID, connection.StatusCode, connection.StatusMessage)
+		}
+		return connection, connection.StatusCode, nil
+	}
+}
+
+// vpcConnectionDeleteStateRefreshFunc reports a missing connection as deleted
+func vpcConnectionDeleteStateRefreshFunc(client *VpcPeeringClient, datacenterID, connectionID string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		connection, err := client.Get(datacenterID, connectionID)
+		if err != nil {
+			if IsNotFound(err) {
+				return &VpcPeer{ID: connectionID, ClusterDatacenterID: datacenterID, StatusCode: "deleted"}, "deleted", nil
+			}
+			return nil, "", err
+		}
 		return connection, connection.StatusCode, nil
 	}
 }
@@ -2,10 +2,13 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -42,6 +45,61 @@ func TestAccInstaclustrVpcPeeringConnection_basic(t *testing.T) {
 	})
 }
 
+func TestVpcConnectionStateRefreshFunc_failed(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"pcx","statusCode":"failed","statusMessage":"Overlapping CIDR"}`))
+	})
+	defer done()
+
+	_, _, err := vpcConnectionStateRefreshFunc(client.VpcPeeringClient(), "dc", "pcx")()
+	if err == nil || !strings.Contains(err.Error(), "failed: Overlapping CIDR") {
+		t.Fatalf("expected failed peering error, got %v", err)
+	}
+}
+
+func TestResourceInstaclustrVpcPeeringConnectionCreate_failedWaitKeepsID(t *testing.T) {
+	client, done := testClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"pcx"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"pcx","statusCode":"failed","statusMessage":"Overlapping CIDR"}`))
+	})
+	defer done()
+
+	d := schema.TestResourceDataRaw(t, resourceVpcPeeringConnection().Schema, testVpcPeeringConnectionRawConfig)
+	err := resourceInstaclustrVpcPeeringConnectionCreate(d, client)
+	if err == nil || !strings.Contains(err.Error(), "Overlapping CIDR") {
+		t.Fatalf("expected failed wait error, got %v", err)
+	}
+	if d.Id() != vpcPeeringConnectionID("0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11", "pcx") {
+		t.Fatalf("expected VPC peering connection to stay in state after a failed wait, got %q", d.Id())
+	}
+}
+
+func TestResourceInstaclustrVpcPeeringConnectionDelete_waits(t *testing.T) {
+	status := func(s string) string { return fmt.Sprintf(`{"id":"pcx","statusCode":"%s"}`, s) }
+	testResourceDelete(t, resourceVpcPeeringConnection(), testVpcPeeringConnectionRawConfig, vpcPeeringConnectionID("dc", "pcx"), map[string]testDeleteCase{
+		"already deleted": {http.StatusNotFound, nil},
+		"deleting":        {http.StatusAccepted, []string{status("active"), status("deleting"), status("deleted")}},
+		"removed":         {http.StatusAccepted, []string{status("deleting"), ""}},
+	})
+}
+
+func TestResourceInstaclustrVpcPeeringConnectionDelete_error(t *testing.T) {
+	testResourceDeleteError(t, resourceVpcPeeringConnection(), testVpcPeeringConnectionRawConfig, vpcPeeringConnectionID("dc", "pcx"))
+}
+
+var testVpcPeeringConnectionRawConfig = map[string]interface{}{
+	"peer_vpc_id":           "vpc-1a2b3c4d",
+	"peer_account_id":       "123456789012",
+	"peer_subnet":           "10.1.0.0/16",
+	"cluster_datacenter_id": "0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11",
+}
+
 func testAccCheckInstaclustrVpcPeeringConnectionExists(n string, pc *VpcPeer) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -71,8 +129,15 @@ func testAccCheckInstaclustrVpcPeeringConnectionDestroy(s *terraform.State) erro
 		if rs.Type != "instaclustr_vpc_peering_connection" {
 			continue
 		}
-		connection, err := client.Get(rs.Primary.Attributes["cluster_datacenter_id"], rs.Primary.ID)
-		if err == nil && connection != nil {
+		clusterDataCenterID, id, _ := splitVpcPeeringConnectionID(rs.Primary.ID)
+		connection, err := client.Get(clusterDataCenterID, id)
+		if err != nil {
+			if IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if connection != nil {
 			return fmt.Errorf("VPC Peering Connecting still exists")
 		}
 	}
@@ -2,10 +2,20 @@ package main
 
 import (
 	"fmt"
+	"net"
+	"regexp"
 
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
+var (
+	uuidPattern            = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	vpcIDPattern           = regexp.MustCompile(`^vpc-([0-9a-f]{8}|[0-9a-f]{17})$`)
+	securityGroupIDPattern = regexp.MustCompile(`^sg-([0-9a-f]{8}|[0-9a-f]{17})$`)
+	awsAccountIDPattern    = regexp.MustCompile(`^[0-9]{12}$`)
+	kmsKeyARNPattern       = regexp.MustCompile(`^arn:aws[a-z-]*:kms:[a-z0-9-]+:[0-9]{12}:key/[0-9a-fA-F-]{36}$`)
+)
+
 func stringInList(values []string) schema.SchemaValidateFunc {
 	return func(v interface{}, k string) (we []string, errors []error) {
 		value := v.(string)
@@ -23,3 +33,106 @@ func stringInList(values []string) schema.SchemaValidateFunc {
 		return
 	}
 }
+
+// validateIntAtLeast checks that an integer is no less than min
+func validateIntAtLeast(min int) schema.SchemaValidateFunc {
+	return func(v interface{}, k string) (we []string, errors []error) {
+		if value := v.(int); value < min {
+			errors = append(errors, fmt.Errorf("%d is less than %d for argument %s", value, min, k))
+		}
+		return
+	}
+}
+
+// validateCIDR checks that a value is an IPv4 CIDR block written in its
+// canonical form, so 10.1.0.5/16 is rejected in favour of 10.1.0.0/16
+func validateCIDR(v interface{}, k string) (we []string, errors []error) {
+	value := v.(string)
+	_, network, err := net.ParseCIDR(value)
+	if err != nil || network.IP.To4() == nil {
+		errors = append(errors, fmt.Errorf("%s is not a valid IPv4 CIDR block for argument %s", value, k))
+		return
+	}
+	if network.String() != value {
+		errors = append(errors, fmt.Errorf("%s is not a canonical CIDR block for argument %s, did you mean %s", value, k, network))
+	}
+	return
+}
+
+func validateUUID(v interface{}, k string) (we []string, errors []error) {
+	return validatePattern(uuidPattern, "UUID")(v, k)
+}
+
+func validateVpcID(v interface{}, k string) (we []string, errors []error) {
+	return validatePattern(vpcIDPattern, "VPC ID")(v, k)
+}
+
+func validateSecurityGroupID(v interface{}, k string) (we []string, errors []error) {
+	return validatePattern(securityGroupIDPattern, "security group ID")(v, k)
+}
+
+func validateAwsAccountID(v interface{}, k string) (we []string, errors []error) {
+	return validatePattern(awsAccountIDPattern, "12 digit AWS account ID")(v, k)
+}
+
+// validateKmsKey accepts a KMS key either by its UUID or its ARN
+func validateKmsKey(v interface{}, k string) (we []string, errors []error) {
+	value := v.(string)
+	if !uuidPattern.MatchString(value) && !kmsKeyARNPattern.MatchString(value) {
+		errors = append(errors, fmt.Errorf("%s is not a valid KMS key ID or ARN for argument %s", value, k))
+	}
+	return
+}
+
+func validatePattern(pattern *regexp.Regexp, name string) schema.SchemaValidateFunc {
+	return func(v interface{}, k string) (we []string, errors []error) {
+		value := v.(string)
+		if !pattern.MatchString(value) {
+			errors = append(errors, fmt.Errorf("%s is not a valid %s for argument %s", value, name, k))
+		}
+		return
+	}
+}
+
+func expandStringSet(set *schema.Set) []string {
+	values := []string{}
+	for _, v := range set.List() {
+		values = append(values, v.(string))
+	}
+	return values
+}
+
+// stringsDifference returns the values in a that are not in b
+func stringsDifference(a, b []string) []string {
+	result := []string{}
+	for _, v := range a {
+		found := false
+		for _, w := range b {
+			if v == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func expandStringList(list []interface{}) []string {
+	values := []string{}
+	for _, v := range list {
+		values = append(values, v.(string))
+	}
+	return values
+}
+
+func stringInSlice(value string, values []string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
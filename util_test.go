@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestValidateIntAtLeast(t *testing.T) {
+	validate := validateIntAtLeast(0)
+	if _, errs := validate(0, "max_retries"); len(errs) > 0 {
+		t.Fatalf("expected 0 to be valid, got %v", errs)
+	}
+	if _, errs := validate(-1, "max_retries"); len(errs) == 0 {
+		t.Fatal("expected -1 to be invalid")
+	}
+}
+
+func TestValidators(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Validate schema.SchemaValidateFunc
+		Valid    []string
+		Invalid  []string
+	}{
+		{
+			Name:     "CIDR",
+			Validate: validateCIDR,
+			Valid:    []string{"10.1.0.0/16", "192.168.1.10/32"},
+			Invalid:  []string{"10.1.0.5/16", "10.1.0.0", "10.1.0.0/33", "2001:db8::/32"},
+		},
+		{
+			Name:     "UUID",
+			Validate: validateUUID,
+			Valid:    []string{"0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11"},
+			Invalid:  []string{"0c2f8d87-36a0-4d26-9a1b", "cluster"},
+		},
+		{
+			Name:     "VPC ID",
+			Validate: validateVpcID,
+			Valid:    []string{"vpc-1a2b3c4d", "vpc-0123456789abcdef0"},
+			Invalid:  []string{"1a2b3c4d", "vpc-1a2b", "sg-1a2b3c4d"},
+		},
+		{
+			Name:     "security group ID",
+			Validate: validateSecurityGroupID,
+			Valid:    []string{"sg-1a2b3c4d", "sg-0123456789abcdef0"},
+			Invalid:  []string{"vpc-1a2b3c4d", "sg-xyz"},
+		},
+		{
+			Name:     "AWS account ID",
+			Validate: validateAwsAccountID,
+			Valid:    []string{"123456789012"},
+			Invalid:  []string{"12345678901", "1234-5678-9012"},
+		},
+		{
+			Name:     "KMS key",
+			Validate: validateKmsKey,
+			Valid: []string{
+				"0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11",
+				"arn:aws:kms:us-east-1:123456789012:key/0c2f8d87-36a0-4d26-9a1b-0d5f6f3e2c11",
+			},
+			Invalid: []string{"alias/cassandra", "arn:aws:kms:us-east-1:123456789012:alias/cassandra"},
+		},
+	}
+	for _, tc := range cases {
+		for _, value := range tc.Valid {
+			if _, errs := tc.Validate(value, "key"); len(errs) > 0 {
+				t.Fatalf("%s: expected %q to be valid, got %v", tc.Name, value, errs)
+			}
+		}
+		for _, value := range tc.Invalid {
+			if _, errs := tc.Validate(value, "key"); len(errs) == 0 {
+				t.Fatalf("%s: expected %q to be invalid", tc.Name, value)
+			}
+		}
+	}
+}